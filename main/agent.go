@@ -0,0 +1,368 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hydra/pkg/hydra"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Интервал опроса Vault по умолчанию
+const defaultAgentInterval = 60 * time.Second
+
+// agentState - состояние агента для health endpoint
+type agentState struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	started    time.Time
+	lastCheck  time.Time
+	lastChange time.Time
+	lastError  string
+	renders    int
+}
+
+// runAgent запускает агент: один раз авторизуется (токен продлевается в фоне),
+// опрашивает версии секретов и перерисовывает файлы только при изменениях.
+// Работает до SIGINT/SIGTERM и возвращает код завершения
+func runAgent(specs []templateSpec) int {
+	interval, err := agentInterval()
+	if err != nil {
+		Log(Error, err.Error())
+		return exitUsage
+	}
+	client, err := auth(primaryConfig)
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+	}
+
+	state := &agentState{interval: interval, started: time.Now()}
+	server := startHealthServer(setting("HYDRA_AGENT_HEALTH_ADDR"), state)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	Log(Info, fmt.Sprintf("Агент запущен, интервал опроса %s", interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var versions map[string]string
+	for {
+		versions = agentCheck(libClient(client), specs, versions, state)
+		select {
+		case sig := <-signals:
+			Log(Info, fmt.Sprintf("Получен сигнал %s, агент завершает работу", sig))
+			if server != nil {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := server.Shutdown(ctx); err != nil {
+					Log(Error, fmt.Sprintf("Ошибка при остановке health endpoint: %s", err))
+				}
+				cancel()
+			}
+			return exitOK
+		case <-ticker.C:
+		}
+	}
+}
+
+// agentInterval читает HYDRA_AGENT_INTERVAL: длительность Go (30s, 5m) или число секунд
+func agentInterval() (time.Duration, error) {
+	value := setting("HYDRA_AGENT_INTERVAL")
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("некорректное значение HYDRA_AGENT_INTERVAL: %s, ожидается например 60s или 5m", setting("HYDRA_AGENT_INTERVAL"))
+	}
+	return interval, nil
+}
+
+// agentCheck сравнивает версии секретов с предыдущими и при изменении перерисовывает файлы.
+// Возвращает версии, которые считаются отрисованными
+func agentCheck(client hydra.SecretStore, specs []templateSpec, previous map[string]string, state *agentState) map[string]string {
+	current, err := watchedVersions(client, specs)
+	if err != nil {
+		state.fail(err)
+		return previous
+	}
+	changed := changedPaths(previous, current)
+	if previous != nil && len(changed) == 0 {
+		Log(Debug, "Секреты не изменились")
+		state.ok(false)
+		return previous
+	}
+	if previous != nil {
+		Log(Info, fmt.Sprintf("Изменились секреты: %s", strings.Join(changed, ", ")))
+	}
+
+	if vaultSecretPaths != "" {
+		if err := injectSecrets(client); err != nil {
+			state.fail(err)
+			return previous
+		}
+	}
+	if len(specs) > 0 {
+		if err := renderTemplates(client, specs); err != nil {
+			state.fail(err)
+			return previous
+		}
+	}
+	// При первом запуске приложение еще читает файлы само, уведомляем только об изменениях
+	if previous != nil {
+		notifyChange()
+	}
+	state.ok(true)
+	return current
+}
+
+// watchedPaths возвращает пути секретов из VAULT_SECRET_PATH и шаблонов.
+// В рекурсивном режиме пути перечитываются каждый раз, чтобы заметить новые секреты
+func watchedPaths(client hydra.SecretStore, specs []templateSpec) ([]string, error) {
+	seen := map[string]bool{}
+	if vaultSecretPaths != "" {
+		for _, spec := range strings.Split(vaultSecretPaths, " ") {
+			path, _ := splitPathSpec(spec)
+			if checkVaultRecursiveEnv() {
+				found, err := listAllPaths(client, path)
+				if err != nil {
+					return nil, err
+				}
+				for _, recursivePath := range found {
+					seen[normalizeSecretPath(recursivePath)] = true
+				}
+				continue
+			}
+			seen[normalizeSecretPath(path)] = true
+		}
+	}
+	if len(specs) > 0 {
+		_, cache, err := loadTemplates(specs)
+		if err != nil {
+			return nil, err
+		}
+		for path := range cache.wanted {
+			seen[normalizeSecretPath(path)] = true
+		}
+	}
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// watchedVersions возвращает версию каждого отслеживаемого секрета
+func watchedVersions(client hydra.SecretStore, specs []templateSpec) (map[string]string, error) {
+	paths, err := watchedPaths(client, specs)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string, len(paths))
+	for _, path := range paths {
+		versions[path] = secretVersion(client, path)
+	}
+	return versions, nil
+}
+
+// secretVersion возвращает версию секрета из метаданных KV v2 (current_version и updated_time).
+// Для KV v1 или без прав на metadata версией считается хэш содержимого
+func secretVersion(client hydra.SecretStore, path string) string {
+	if _, version, _ := splitVersion(path); version > 0 {
+		// Закрепленная версия не меняется
+		return fmt.Sprintf("v%d", version)
+	}
+	metadata, err := readMetadata(client, path)
+	if err == nil && metadata.CurrentVersion > 0 {
+		return fmt.Sprintf("v%d %s", metadata.CurrentVersion, metadata.UpdatedTime)
+	}
+	if err != nil {
+		Log(Debug, fmt.Sprintf("Метаданные %s недоступны, сравниваем содержимое: %s", path, err))
+	}
+	data, _ := executeKVOperation(client, path, "Read", nil)
+	if data == nil {
+		return "absent"
+	}
+	sum := sha256.Sum256([]byte(strings.Join(data, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// changedPaths возвращает пути, версии которых отличаются, включая появившиеся и удаленные
+func changedPaths(previous, current map[string]string) []string {
+	var changed []string
+	for path, version := range current {
+		if previous[path] != version {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// notifyChange выполняет HYDRA_AGENT_COMMAND и отправляет сигнал процессу из HYDRA_AGENT_PID / HYDRA_AGENT_PID_FILE
+func notifyChange() {
+	if command := setting("HYDRA_AGENT_COMMAND"); command != "" {
+		cmd := exec.Command("sh", "-c", command)
+		if osType == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		}
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			Log(Error, fmt.Sprintf("Команда '%s' завершилась с ошибкой: %s", command, err))
+		} else {
+			Log(Info, fmt.Sprintf("Команда '%s' выполнена", command))
+		}
+	}
+
+	pid, err := agentPID()
+	if err != nil {
+		Log(Error, err.Error())
+		return
+	}
+	if pid == 0 {
+		return
+	}
+	sig, err := parseSignal(setting("HYDRA_AGENT_SIGNAL"))
+	if err != nil {
+		Log(Error, err.Error())
+		return
+	}
+	process, err := os.FindProcess(pid)
+	if err == nil {
+		err = process.Signal(sig)
+	}
+	if err != nil {
+		Log(Error, fmt.Sprintf("Не удалось отправить сигнал %s процессу %d: %s", sig, pid, err))
+		return
+	}
+	Log(Info, fmt.Sprintf("Процессу %d отправлен сигнал %s", pid, sig))
+}
+
+// agentPID возвращает PID из HYDRA_AGENT_PID или из файла HYDRA_AGENT_PID_FILE.
+// Файл читается при каждом изменении, так как процесс мог перезапуститься
+func agentPID() (int, error) {
+	value := setting("HYDRA_AGENT_PID")
+	if file := setting("HYDRA_AGENT_PID_FILE"); value == "" && file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return 0, fmt.Errorf("не удалось прочитать PID из %s: %v", file, err)
+		}
+		value = strings.TrimSpace(string(content))
+	}
+	if value == "" {
+		return 0, nil
+	}
+	pid, err := strconv.Atoi(value)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("некорректный PID: %s", value)
+	}
+	return pid, nil
+}
+
+// parseSignal переводит имя сигнала (HUP, SIGHUP, USR1) в os.Signal
+func parseSignal(name string) (os.Signal, error) {
+	name = strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if sig, ok := agentSignals[name]; ok {
+		return sig, nil
+	}
+	names := make([]string, 0, len(agentSignals))
+	for known := range agentSignals {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("неизвестный сигнал %s, доступны: %s", name, strings.Join(names, ", "))
+}
+
+// startHealthServer запускает health endpoint, если задан HYDRA_AGENT_HEALTH_ADDR
+func startHealthServer(addr string, state *agentState) *http.Server {
+	if addr == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", state.serveHealth)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			Log(Error, fmt.Sprintf("Health endpoint %s остановлен с ошибкой: %s", addr, err))
+		}
+	}()
+	Log(Info, fmt.Sprintf("Health endpoint: http://%s/health", addr))
+	return server
+}
+
+// ok отмечает успешную проверку
+func (s *agentState) ok(rendered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCheck = time.Now()
+	s.lastError = ""
+	if rendered {
+		s.lastChange = s.lastCheck
+		s.renders++
+	}
+}
+
+// fail отмечает неудачную проверку, агент продолжает работу и повторит ее на следующем интервале
+func (s *agentState) fail(err error) {
+	Log(Error, fmt.Sprintf("Ошибка агента: %s", err))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err.Error()
+}
+
+// serveHealth отвечает 200, если последняя проверка была успешной и не старше трех интервалов, иначе 503
+func (s *agentState) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	healthy := s.lastError == "" && !s.lastCheck.IsZero() && time.Since(s.lastCheck) < 3*s.interval
+	body := map[string]interface{}{
+		"status":      "ok",
+		"started":     s.started.Format(time.RFC3339),
+		"last_check":  formatAgentTime(s.lastCheck),
+		"last_change": formatAgentTime(s.lastChange),
+		"renders":     s.renders,
+	}
+	if s.lastError != "" {
+		body["last_error"] = s.lastError
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		body["status"] = "unhealthy"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+func formatAgentTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
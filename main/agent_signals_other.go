@@ -0,0 +1,26 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Сигналы, которые агент может отправить процессу после изменения секретов
+var agentSignals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"KILL": syscall.SIGKILL,
+}
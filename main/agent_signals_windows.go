@@ -0,0 +1,18 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Сигналы, которые агент может отправить процессу. На Windows процессу можно отправить только KILL
+var agentSignals = map[string]os.Signal{
+	"KILL": syscall.SIGKILL,
+}
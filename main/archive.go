@@ -0,0 +1,399 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"golang.org/x/term"
+	"hydra/pkg/hydra"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Версия формата архива, увеличивается при несовместимых изменениях
+const archiveFormatVersion = 1
+
+// Имя манифеста внутри архива
+const archiveManifestName = "manifest.json"
+
+// archiveManifest - оглавление архива: откуда и когда сделан экспорт и контрольные суммы всех секретов
+type archiveManifest struct {
+	Format       int            `json:"format"`
+	Created      string         `json:"created"`
+	HydraVersion string         `json:"hydra_version"`
+	Source       string         `json:"source"`
+	Namespace    string         `json:"namespace,omitempty"`
+	Paths        []string       `json:"paths"`
+	Secrets      []archiveEntry `json:"secrets"`
+}
+
+// archiveEntry - запись манифеста об одном секрете
+type archiveEntry struct {
+	Path      string `json:"path"`
+	File      string `json:"file"`
+	KVVersion int    `json:"kv_version"`
+	Size      int    `json:"size"`
+	SHA256    string `json:"sha256"`
+}
+
+// archiveSecret - секрет в архиве: путь без data/, данные последней версии и метаданные KV v2
+type archiveSecret struct {
+	Path      string                 `json:"path"`
+	KVVersion int                    `json:"kv_version"`
+	Data      map[string]interface{} `json:"data"`
+	Metadata  *kvMetadata            `json:"metadata,omitempty"`
+}
+
+// exportArchive выполняет команду export: читает все секреты из VAULT_BACKUP_PATH
+// и записывает их в зашифрованный архив
+func exportArchive(w io.Writer, file string) error {
+	recipients, err := archiveRecipients()
+	if err != nil {
+		return err
+	}
+	client, err := auth(primaryConfig)
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+	}
+	roots, err := backupRoots(client, backupPath)
+	if err != nil {
+		return err
+	}
+	secrets, err := collectArchiveSecrets(libClient(client), roots)
+	if err != nil {
+		return err
+	}
+
+	manifest := archiveManifest{
+		Format:       archiveFormatVersion,
+		Created:      time.Now().UTC().Format(time.RFC3339),
+		HydraVersion: version,
+		Source:       primaryConfig.VaultAddr,
+		Namespace:    primaryConfig.Namespace,
+		Paths:        roots,
+	}
+	var encrypted bytes.Buffer
+	if err := writeArchive(&encrypted, recipients, &manifest, secrets); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, encrypted.Bytes(), 0600); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(encrypted.Bytes())
+	fmt.Fprintf(w, "Экспортировано секретов: %d из %s в %s\n", len(secrets), describeVault(manifest.Source, manifest.Namespace), file)
+	fmt.Fprintf(w, "sha256 архива: %s\n", hex.EncodeToString(sum[:]))
+	return nil
+}
+
+// collectArchiveSecrets рекурсивно читает секреты и метаданные KV v2 по всем корневым путям
+func collectArchiveSecrets(client hydra.SecretStore, roots []string) ([]archiveSecret, error) {
+	var secrets []archiveSecret
+	for _, root := range roots {
+		paths, err := listAllPaths(client, root)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			Log(Error, fmt.Sprintf("По пути %s не найдено секретов", root))
+		}
+		var included []string
+		for _, path := range paths {
+			if excludeString(path) == nil {
+				Log(Info, fmt.Sprintf("путь '%s' был исключен на основе регулярного выражения", path))
+				continue
+			}
+			included = append(included, path)
+		}
+		// Секреты читаются параллельно, в архив попадают и логируются в порядке путей
+		results, err := runParallel(included, func(path string) (*archiveSecret, error) {
+			secretJSON, err := readSecret(client, path)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка при получении секрета по пути %s: %v", path, err)
+			}
+			if secretJSON == nil {
+				return nil, nil
+			}
+			data, err := unmarshalSecret(secretJSON, path)
+			if err != nil {
+				return nil, err
+			}
+			secret := &archiveSecret{Path: normalizeSecretPath(path), KVVersion: 1, Data: data}
+			// Версия KV определяется по метаданным: engine может быть вложенным (team/kv), и по пути ее не понять
+			switch metadata, err := readMetadata(client, path); {
+			case err == nil:
+				secret.KVVersion, secret.Metadata = 2, metadata
+			case !errors.Is(err, hydra.ErrNotKVv2):
+				return nil, err
+			}
+			return secret, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, secret := range results {
+			if secret == nil {
+				Log(Error, fmt.Sprintf("Секрет %s пропал во время экспорта, пропускаем", included[i]))
+				continue
+			}
+			secrets = append(secrets, *secret)
+			Log(Debug, fmt.Sprintf("Секрет %s добавлен в архив", secret.Path))
+		}
+	}
+	return secrets, nil
+}
+
+// writeArchive пишет tar.gz с манифестом и секретами и шифрует его age.
+// Манифест идет первым, контрольные суммы в нем считаются по JSON каждого секрета
+func writeArchive(w io.Writer, recipients []age.Recipient, manifest *archiveManifest, secrets []archiveSecret) error {
+	files := make([][]byte, len(secrets))
+	manifest.Secrets = make([]archiveEntry, len(secrets))
+	for i, secret := range secrets {
+		content, err := json.MarshalIndent(secret, "", "  ")
+		if err != nil {
+			return fmt.Errorf("ошибка при сериализации секрета %s: %v", secret.Path, err)
+		}
+		sum := sha256.Sum256(content)
+		files[i] = content
+		manifest.Secrets[i] = archiveEntry{
+			Path:      secret.Path,
+			File:      fmt.Sprintf("secrets/%05d.json", i),
+			KVVersion: secret.KVVersion,
+			Size:      len(content),
+			SHA256:    hex.EncodeToString(sum[:]),
+		}
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return fmt.Errorf("ошибка при шифровании архива: %v", err)
+	}
+	gz := gzip.NewWriter(encrypted)
+	tw := tar.NewWriter(gz)
+	modTime := time.Now()
+	if err := addTarFile(tw, archiveManifestName, manifestJSON, modTime); err != nil {
+		return err
+	}
+	for i, entry := range manifest.Secrets {
+		if err := addTarFile(tw, entry.File, files[i], modTime); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return encrypted.Close()
+}
+
+func addTarFile(tw *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("ошибка при записи %s в архив: %v", name, err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("ошибка при записи %s в архив: %v", name, err)
+	}
+	return nil
+}
+
+// archiveRecipients возвращает получателей age из HYDRA_AGE_RECIPIENTS / HYDRA_AGE_RECIPIENTS_FILE
+// или ключ из пароля (scrypt). Без шифрования архив не создается
+func archiveRecipients() ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, key := range strings.Fields(setting("HYDRA_AGE_RECIPIENTS")) {
+		recipient, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный получатель age %s: %v", key, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if file := setting("HYDRA_AGE_RECIPIENTS_FILE"); file != "" {
+		content, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать получателей age из %s: %v", file, err)
+		}
+		defer content.Close()
+		parsed, err := age.ParseRecipients(content)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный файл получателей age %s: %v", file, err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+
+	passphraseSet := setting("HYDRA_ARCHIVE_PASSPHRASE") != "" || setting("HYDRA_ARCHIVE_PASSPHRASE_FILE") != ""
+	if len(recipients) > 0 {
+		if passphraseSet {
+			return nil, fmt.Errorf("архив шифруется либо ключами age, либо паролем: задайте только HYDRA_AGE_RECIPIENTS или HYDRA_ARCHIVE_PASSPHRASE")
+		}
+		return recipients, nil
+	}
+	passphrase, err := archivePassphrase(true)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return []age.Recipient{recipient}, nil
+}
+
+// archivePassphrase возвращает пароль архива из переменной или файла, а при подключенном терминале запрашивает его без эха.
+// С confirm (шифрование) пароль из терминала запрашивается второй раз: с опечаткой архив было бы уже не расшифровать
+func archivePassphrase(confirm bool) (string, error) {
+	if passphrase := setting("HYDRA_ARCHIVE_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if file := setting("HYDRA_ARCHIVE_PASSPHRASE_FILE"); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("не удалось прочитать пароль архива из файла %s: %v", file, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdinFd) {
+		return "", fmt.Errorf("не задан ключ архива: HYDRA_AGE_RECIPIENTS (HYDRA_AGE_IDENTITY_FILE для расшифровки), HYDRA_ARCHIVE_PASSPHRASE или HYDRA_ARCHIVE_PASSPHRASE_FILE")
+	}
+	passphrase, err := promptArchivePassphrase(stdinFd, "Пароль архива: ")
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("пустой пароль архива")
+	}
+	if confirm {
+		repeated, err := promptArchivePassphrase(stdinFd, "Повторите пароль архива: ")
+		if err != nil {
+			return "", err
+		}
+		if repeated != passphrase {
+			return "", fmt.Errorf("пароли архива не совпадают")
+		}
+	}
+	return passphrase, nil
+}
+
+// promptArchivePassphrase выводит приглашение в stderr и читает пароль из терминала без эха
+func promptArchivePassphrase(fd int, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("не удалось прочитать пароль из терминала: %v", err)
+	}
+	return string(passphrase), nil
+}
+
+// defaultArchiveFile возвращает имя архива по умолчанию с датой экспорта
+func defaultArchiveFile() string {
+	return fmt.Sprintf("hydra-backup-%s.tar.gz.age", time.Now().Format("20060102-150405"))
+}
+
+// readArchive расшифровывает архив и проверяет контрольные суммы всех секретов по манифесту
+func readArchive(file string) (*archiveManifest, []archiveSecret, error) {
+	identities, err := archiveIdentities()
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось открыть архив %s: %v", file, err)
+	}
+	defer f.Close()
+	decrypted, err := age.Decrypt(f, identities...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось расшифровать архив %s: %v", file, err)
+	}
+	gz, err := gzip.NewReader(decrypted)
+	if err != nil {
+		return nil, nil, fmt.Errorf("архив %s поврежден: %v", file, err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("архив %s поврежден: %v", file, err)
+		}
+		if files[header.Name], err = io.ReadAll(tr); err != nil {
+			return nil, nil, fmt.Errorf("архив %s поврежден: %v", file, err)
+		}
+	}
+
+	var manifest archiveManifest
+	if err := json.Unmarshal(files[archiveManifestName], &manifest); err != nil {
+		return nil, nil, fmt.Errorf("в архиве %s нет корректного %s: %v", file, archiveManifestName, err)
+	}
+	if manifest.Format != archiveFormatVersion {
+		return nil, nil, fmt.Errorf("версия формата архива %d не поддерживается, ожидается %d", manifest.Format, archiveFormatVersion)
+	}
+	secrets := make([]archiveSecret, 0, len(manifest.Secrets))
+	for _, entry := range manifest.Secrets {
+		content, ok := files[entry.File]
+		if !ok {
+			return nil, nil, fmt.Errorf("в архиве нет файла %s секрета %s", entry.File, entry.Path)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("контрольная сумма секрета %s не совпадает с манифестом", entry.Path)
+		}
+		var secret archiveSecret
+		if err := json.Unmarshal(content, &secret); err != nil {
+			return nil, nil, fmt.Errorf("ошибка при чтении секрета %s из архива: %v", entry.Path, err)
+		}
+		secrets = append(secrets, secret)
+	}
+	Log(Info, fmt.Sprintf("Архив %s от %s из %s, секретов: %d", file, manifest.Created, describeVault(manifest.Source, manifest.Namespace), len(secrets)))
+	return &manifest, secrets, nil
+}
+
+// archiveIdentities возвращает ключи для расшифровки: файл ключей age HYDRA_AGE_IDENTITY_FILE или пароль архива
+func archiveIdentities() ([]age.Identity, error) {
+	if file := setting("HYDRA_AGE_IDENTITY_FILE"); file != "" {
+		content, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось прочитать ключи age из %s: %v", file, err)
+		}
+		defer content.Close()
+		identities, err := age.ParseIdentities(content)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный файл ключей age %s: %v", file, err)
+		}
+		return identities, nil
+	}
+	passphrase, err := archivePassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return []age.Identity{identity}, nil
+}
@@ -0,0 +1,67 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"hydra/pkg/hydra/vaulttest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportRestoreRoundTrip(t *testing.T) {
+	source := vaulttest.NewServer(t)
+	source.Mount("legacy", 1)
+	source.Put("secret/app/db", map[string]interface{}{"USER": "app", "PASSWORD": "p1"})
+	source.SetMetadata("secret/app/db", map[string]interface{}{
+		"custom_metadata":      map[string]interface{}{"owner": "team-a"},
+		"max_versions":         5,
+		"cas_required":         true,
+		"delete_version_after": "720h0m0s",
+	})
+	source.Put("legacy/app/x", map[string]interface{}{"A": "1"})
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.tar.gz.age")
+	writeTestFile(t, filepath.Join(dir, "passphrase"), "secret-passphrase\n")
+	code, stdout, stderr := runHydra(t, dir, map[string]string{
+		"VAULT_ADDR":                    source.URL,
+		"VAULT_TOKEN":                   source.RootToken(),
+		"VAULT_BACKUP_PATH":             "secret legacy",
+		"HYDRA_ARCHIVE_PASSPHRASE_FILE": filepath.Join(dir, "passphrase"),
+	}, "export", archive)
+	if code != exitOK {
+		t.Fatalf("export: код завершения %d\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+	}
+
+	target := vaulttest.NewServer(t)
+	target.Mount("legacy", 1)
+	code, stdout, stderr = runHydra(t, dir, map[string]string{
+		"VAULT_ADDR":                    target.URL,
+		"VAULT_TOKEN":                   target.RootToken(),
+		"HYDRA_ARCHIVE_PASSPHRASE_FILE": filepath.Join(dir, "passphrase"),
+	}, "restore", archive)
+	if code != exitOK {
+		t.Fatalf("restore: код завершения %d\nstdout:\n%s\nstderr:\n%s", code, stdout, stderr)
+	}
+
+	for _, path := range []string{"secret/app/db", "legacy/app/x"} {
+		if got, want := target.Get(path), source.Get(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v, ожидалось %v", path, got, want)
+		}
+	}
+	got, want := target.Metadata("secret/app/db"), source.Metadata("secret/app/db")
+	for _, key := range []string{"custom_metadata", "max_versions", "cas_required", "delete_version_after"} {
+		if !reflect.DeepEqual(got[key], want[key]) {
+			t.Errorf("метаданные %s: %v, ожидалось %v", key, got[key], want[key])
+		}
+	}
+	if target.Metadata("legacy/app/x") != nil || target.Get("legacy/metadata/app/x") != nil {
+		t.Errorf("для KV v1 записаны метаданные")
+	}
+}
@@ -8,10 +8,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	vault "github.com/hashicorp/vault/api"
+	"golang.org/x/term"
+	"hydra/pkg/hydra"
 	"io"
 	"os"
+	"strings"
+	"sync"
 )
 
 // Структура для конфигурации авторизации
@@ -22,6 +27,26 @@ type AuthConfig struct {
 	IDToken    string
 	AuthUrl    string
 	VaultRole  string
+	Namespace  string // Namespace Vault Enterprise (заголовок X-Vault-Namespace), пустой - root namespace
+
+	// AppRole
+	RoleID          string // role_id роли AppRole
+	SecretID        string // secret_id в открытом виде
+	SecretIDFile    string // Файл, из которого читается secret_id
+	SecretIDWrapped bool   // secret_id передан как wrapping токен и его нужно развернуть
+	AppRolePath     string // Путь монтирования AppRole (по умолчанию approle)
+
+	// TLS Certificate
+	CertAuth     bool   // Авторизация по клиентскому сертификату
+	CertRole     string // Имя роли cert auth (не обязательно)
+	CertAuthPath string // Путь монтирования cert auth (по умолчанию cert)
+
+	// Userpass / LDAP
+	Username     string // Имя пользователя
+	Password     string // Пароль в открытом виде
+	PasswordFile string // Файл, из которого читается пароль
+	LoginMethod  string // Метод авторизации: userpass или ldap (по умолчанию userpass)
+	LoginPath    string // Путь монтирования метода (по умолчанию совпадает с LoginMethod)
 }
 
 // Функция для аутентификации в Vault
@@ -29,51 +54,108 @@ func auth(authConfig AuthConfig) (*vault.Client, error) {
 	// Проверяем, что адрес Vault задан
 	if authConfig.VaultAddr == "" {
 		Log(Error, "не задан адрес Vault")
-		os.Exit(10)
+		exit(10)
+	}
+	Log(Info, fmt.Sprintf("Авторизуемся в %s", describeVault(authConfig.VaultAddr, authConfig.Namespace)))
+	options, err := authOptions(authConfig)
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
 	}
-	Log(Info, fmt.Sprintf("Авторизуемся в %s", authConfig.VaultAddr))
-	// Получаем клиента Vault
-	client, err := createClient(authConfig.VaultAddr)
+	// Получаем клиента Vault. Namespace применяется и к авторизации, и ко всем последующим запросам клиента
+	client, err := createClient(authConfig, options)
 	if err != nil {
-		Log(Error, fmt.Sprintf("Ошибка при создании клиента Vault: %s", err))
-		HandleError(err, "", 10)
+		exitWith(exitAuth, fmt.Errorf("Ошибка при создании клиента Vault: %w", err))
+	}
+
+	// Токен из VAULT_TOKEN проверяется через lookup-self. Такой токен выдан пользователем,
+	// поэтому Hydra его не продлевает и не отзывает
+	loginResp, err := client.Login(context.Background())
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+	}
+	if loginResp != nil {
+		// Токен создан Hydra - продлеваем его в фоне и отзываем при завершении
+		manageToken(client, authConfig, loginResp)
+	}
+	return client.Vault(), nil
+}
+
+// authOptions переводит AuthConfig в параметры авторизации библиотеки. secret_id и пароль
+// читаются из файлов здесь же, пароль при необходимости запрашивается в терминале
+func authOptions(authConfig AuthConfig) (hydra.AuthOptions, error) {
+	options := hydra.AuthOptions{
+		Token:           authConfig.VaultToken,
+		RoleID:          authConfig.RoleID,
+		SecretIDWrapped: authConfig.SecretIDWrapped,
+		AppRolePath:     authConfig.AppRolePath,
+		Cert:            authConfig.CertAuth,
+		CertRole:        authConfig.CertRole,
+		CertPath:        authConfig.CertAuthPath,
+		Username:        authConfig.Username,
+		LoginMethod:     authConfig.LoginMethod,
+		LoginPath:       authConfig.LoginPath,
+		JWT:             selectToken(authConfig),
+		JWTPath:         authConfig.AuthUrl,
+		Role:            authConfig.VaultRole,
+	}
+	if options.JWTPath == "" {
+		// Путь авторизации по JWT выбирается по типу токена
+		options.JWTPath = selectAuthPathByToken(authConfig)
 	}
 
-	// Если в конфиге задан путь авторизации (authPath), используем его
-	authPath := authConfig.AuthUrl
-	if authPath == "" {
-		// Иначе выбираем путь на основе типа токена
-		authPath = selectAuthPathByToken(authConfig)
+	// Секреты читаются только для способа, который будет выбран по приоритету
+	var err error
+	switch {
+	case options.Token != "":
+	case options.RoleID != "":
+		options.SecretID, err = resolveSecretID(authConfig)
+	case options.Cert:
+	case options.Username != "":
+		options.Password, err = resolvePassword(authConfig)
 	}
-	// Если используется Vault Token, проверяем его с помощью lookup-self
-	if authConfig.VaultToken != "" {
-		client.SetToken(authConfig.VaultToken)
-		_, err := client.Logical().Read("auth/token/lookup-self")
+	return options, err
+}
+
+// resolveSecretID возвращает secret_id (или wrapping токен secret_id) из переменной или файла
+func resolveSecretID(authConfig AuthConfig) (string, error) {
+	secretID := authConfig.SecretID
+	if secretID == "" && authConfig.SecretIDFile != "" {
+		content, err := os.ReadFile(authConfig.SecretIDFile)
 		if err != nil {
-			Log(Error, fmt.Sprintf("Ошибка при проверке токена: %s", err))
-			os.Exit(10)
+			return "", fmt.Errorf("не удалось прочитать secret_id из файла %s: %v", authConfig.SecretIDFile, err)
 		}
-		return client, nil
+		secretID = strings.TrimSpace(string(content))
+	}
+	if secretID == "" {
+		return "", fmt.Errorf("не задан secret_id для роли %s", authConfig.RoleID)
 	}
+	return secretID, nil
+}
 
-	// Если не Vault Token, то аутентификация с использованием другого токена (K8s или ID)
-	token := selectToken(authConfig)
-	if token == "" {
-		HandleError(err, "не выбран токен для аутентификации", 1)
+// resolvePassword возвращает пароль из переменной или файла, а при подключенном терминале запрашивает его без эха
+func resolvePassword(authConfig AuthConfig) (string, error) {
+	if authConfig.Password != "" {
+		return authConfig.Password, nil
+	}
+	if authConfig.PasswordFile != "" {
+		content, err := os.ReadFile(authConfig.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("не удалось прочитать пароль из файла %s: %v", authConfig.PasswordFile, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
 	}
 
-	// Аутентификация с K8s или ID Token
-	loginResp, err := client.Logical().Write(authPath, map[string]interface{}{
-		"jwt":  token,
-		"role": authConfig.VaultRole,
-	})
+	stdinFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdinFd) {
+		return "", fmt.Errorf("не задан пароль для пользователя %s и терминал недоступен для ввода", authConfig.Username)
+	}
+	fmt.Fprintf(os.Stderr, "Пароль для %s (%s): ", authConfig.Username, authConfig.VaultAddr)
+	password, err := term.ReadPassword(stdinFd)
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
-		Log(Error, fmt.Sprintf("Ошибка при аутентификации с токеном: %s", err))
-		HandleError(err, "", 10)
+		return "", fmt.Errorf("не удалось прочитать пароль из терминала: %v", err)
 	}
-
-	client.SetToken(loginResp.Auth.ClientToken)
-	return client, nil
+	return string(password), nil
 }
 
 // Определяет путь аутентификации на основе доступных токенов
@@ -105,24 +187,71 @@ func selectToken(authConfig AuthConfig) string {
 }
 
 // Функция для создания клиента Vault
-func createClient(vaultAddr string) (*vault.Client, error) {
-	clientConfig := &vault.Config{Address: vaultAddr}
+func createClient(authConfig AuthConfig, options hydra.AuthOptions) (*hydra.Client, error) {
 	_, tlsConfig, err := configureTLS(certsPath, false)
 	if err != nil {
 		HandleError(err, "ошибка настройки TLS:", 1)
 	}
+	// Токен и namespace берутся только из AuthConfig, VAULT_TOKEN и VAULT_NAMESPACE клиентом не подхватываются
+	return hydra.NewClient(hydra.Options{
+		Address:     authConfig.VaultAddr,
+		Namespace:   authConfig.Namespace,
+		TLS:         tlsConfig,
+		Auth:        options,
+		Retry:       currentRetryPolicy(),
+		Concurrency: concurrency(),
+		RateLimit:   rateLimit(),
+		Log:         hydraLog,
+	})
+}
 
-	err = clientConfig.ConfigureTLS(tlsConfig)
-	if err != nil {
-		HandleError(err, "ошибка при конфигурации TLS:", 1)
+// libClients - клиенты библиотеки по клиентам Vault. Клиент библиотеки хранит список engine,
+// поэтому создается один раз, а не при каждой операции
+var libClients sync.Map
+
+// libClient возвращает клиент библиотеки для уже авторизованного клиента Vault
+func libClient(client *vault.Client) *hydra.Client {
+	if lib, ok := libClients.Load(client); ok {
+		return lib.(*hydra.Client)
 	}
+	lib, _ := libClients.LoadOrStore(client, hydra.Wrap(client, hydra.Options{Concurrency: concurrency(), Log: hydraLog}))
+	return lib.(*hydra.Client)
+}
 
-	client, err := vault.NewClient(clientConfig)
+// secretStore возвращает хранилище секретов: файл JSON или YAML, если он задан, иначе Vault
+// с авторизацией по config. Файл позволяет запускать команды без Vault, например локально и в тестах
+func secretStore(config AuthConfig, file string) hydra.SecretStore {
+	if file == "" {
+		client, err := auth(config)
+		if err != nil {
+			exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+		}
+		return libClient(client)
+	}
+	Log(Info, fmt.Sprintf("Секреты читаются из файла %s вместо Vault", file))
+	store, err := hydra.OpenFileStore(file)
 	if err != nil {
-		Log(Error, fmt.Sprintf("ошибка при создании клиента Vault: %s", err))
-		HandleError(err, "", 10)
+		HandleError(err, "Ошибка при открытии файла секретов", Error)
+	}
+	return store
+}
+
+// describeStore возвращает адрес Vault или путь к файлу секретов для логов
+func describeStore(config AuthConfig, file string) string {
+	if file != "" {
+		return "file " + file
 	}
-	return client, nil
+	return describeVault(config.VaultAddr, config.Namespace)
+}
+
+// hydraLog выводит сообщения библиотеки в журнал Hydra
+func hydraLog(level hydra.LogLevel, message string) {
+	Log(int(level), message)
+}
+
+// describeVault возвращает адрес Vault вместе с namespace для логов
+func describeVault(vaultAddr, namespace string) string {
+	return hydra.DescribeVault(vaultAddr, namespace)
 }
 
 // Читает token serviceaccount внутри пода для авторизации по k8s
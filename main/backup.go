@@ -0,0 +1,74 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	vault "github.com/hashicorp/vault/api"
+	"hydra/pkg/hydra"
+	"io"
+	"os"
+	"strings"
+)
+
+// Режимы резервного копирования
+const (
+	backupModeFull        = hydra.BackupFull        // engine пересоздается и все секреты копируются заново
+	backupModeIncremental = hydra.BackupIncremental // копируются только изменившиеся секреты, engine не удаляется
+)
+
+// backupSecrets выполняет команду backup: копирует секреты по путям VAULT_BACKUP_PATH
+// из основного Vault во вторичный
+func backupSecrets(backupPath string) {
+	source := describeStore(primaryConfig, secretsFile)
+	target := describeStore(secondaryConfig, SecSecretsFile)
+	if source == target {
+		Log(Error, "Адреса вольтов не должны совпадать! проверьте переменные VAULT_ADDR SEC_VAULT_ADDR VAULT_NAMESPACE SEC_VAULT_NAMESPACE HYDRA_SECRETS_FILE SEC_HYDRA_SECRETS_FILE")
+		exit(exitError)
+	}
+	Log(Info, fmt.Sprintf("Резервное копирование %s из %s в %s", backupPath, source, target))
+	src := secretStore(primaryConfig, secretsFile)
+	dst := secretStore(secondaryConfig, SecSecretsFile)
+
+	summaries, err := hydra.Backup(context.Background(), src, dst, hydra.BackupOptions{
+		Paths:   strings.Fields(backupPath),
+		Mode:    setting("HYDRA_BACKUP_MODE"),
+		Prune:   checkBoolEnv("HYDRA_BACKUP_PRUNE"),
+		Staging: checkBoolEnv("HYDRA_BACKUP_STAGING"),
+		History: checkBoolEnv("HYDRA_BACKUP_HISTORY"),
+		Exclude: excluded,
+	})
+	// Итог по уже скопированным engine выводится и при ошибке
+	printBackupSummary(os.Stdout, summaries)
+	if errors.Is(err, hydra.ErrSameVault) {
+		Log(Error, "ClusterID вольтов не должны совпадать! проверьте переменные VAULT_ADDR SEC_VAULT_ADDR VAULT_NAMESPACE SEC_VAULT_NAMESPACE")
+		exit(exitError)
+	}
+	if err != nil {
+		HandleError(err, "Резервное копирование прервано", Error)
+	}
+}
+
+// backupRoots возвращает все пути из VAULT_BACKUP_PATH, * раскрывается в список engine KV
+func backupRoots(client *vault.Client, spec string) ([]string, error) {
+	return libClient(client).BackupRoots(context.Background(), strings.Fields(spec))
+}
+
+// excluded сообщает, что путь попадает под VAULT_EXCLUDE_REGEX
+func excluded(path string) bool {
+	return excludeString(path) == nil
+}
+
+// printBackupSummary выводит итог копирования по каждому engine
+func printBackupSummary(w io.Writer, summaries []*hydra.BackupSummary) {
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s: создано %d, изменено %d, без изменений %d, удалено %d\n", s.Mount, s.Created, s.Updated, s.Unchanged, s.Deleted)
+	}
+}
@@ -0,0 +1,124 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"hydra/pkg/hydra/vaulttest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBackupCommand(t *testing.T) {
+	app := map[string]interface{}{"USER": "app", "PASSWORD": "p1"}
+	tests := []struct {
+		name        string
+		args        []string
+		existing    map[string]map[string]interface{} // секреты во вторичном Vault до запуска
+		sameVault   bool                              // вторичный Vault совпадает с основным
+		toFile      bool                              // копия пишется в --sec-secrets-file
+		wantCode    int
+		wantSummary string
+		wantPaths   []string // секреты engine secret во вторичном Vault после запуска
+	}{
+		{
+			name:        "полная копия",
+			args:        []string{"--mode", "full"},
+			existing:    map[string]map[string]interface{}{"secret/old": {"A": "1"}},
+			wantSummary: "secret: создано 2, изменено 0, без изменений 0, удалено 0",
+			wantPaths:   []string{"secret/app/api", "secret/app/db"},
+		},
+		{
+			name:        "инкрементальная копия",
+			args:        []string{"--mode", "incremental"},
+			existing:    map[string]map[string]interface{}{"secret/app/db": app, "secret/old": {"A": "1"}},
+			wantSummary: "secret: создано 1, изменено 0, без изменений 1, удалено 0",
+			wantPaths:   []string{"secret/.hydra-backup-state", "secret/app/api", "secret/app/db", "secret/old"},
+		},
+		{
+			name:        "инкрементальная копия с удалением",
+			args:        []string{"--mode", "incremental", "--prune"},
+			existing:    map[string]map[string]interface{}{"secret/app/db": {"USER": "app"}, "secret/old": {"A": "1"}},
+			wantSummary: "secret: создано 1, изменено 1, без изменений 0, удалено 1",
+			wantPaths:   []string{"secret/.hydra-backup-state", "secret/app/api", "secret/app/db"},
+		},
+		{
+			name:      "тот же Vault",
+			args:      []string{"--mode", "incremental"},
+			sameVault: true,
+			wantCode:  exitError,
+			wantPaths: []string{"secret/app/api", "secret/app/db"},
+		},
+		{
+			name:        "копия в файл",
+			args:        []string{"--mode", "incremental"},
+			toFile:      true,
+			wantSummary: "secret: создано 2, изменено 0, без изменений 0, удалено 0",
+		},
+		{
+			name:     "staging в файл",
+			args:     []string{"--mode", "incremental", "--staging"},
+			toFile:   true,
+			wantCode: exitError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := vaulttest.NewServer(t)
+			primary.Put("secret/app/db", app)
+			primary.Put("secret/app/api", map[string]interface{}{"TOKEN": "t1"})
+			sec := vaulttest.NewServer(t)
+			if tt.sameVault {
+				sec = primary
+			}
+			for path, data := range tt.existing {
+				sec.Put(path, data)
+			}
+			dir := t.TempDir()
+			env := map[string]string{
+				"VAULT_ADDR":        primary.URL,
+				"VAULT_TOKEN":       primary.RootToken(),
+				"SEC_VAULT_ADDR":    sec.URL,
+				"SEC_VAULT_TOKEN":   sec.RootToken(),
+				"VAULT_BACKUP_PATH": "secret",
+			}
+			file := filepath.Join(dir, "backup.json")
+			if tt.toFile {
+				delete(env, "SEC_VAULT_ADDR")
+				delete(env, "SEC_VAULT_TOKEN")
+				env["SEC_HYDRA_SECRETS_FILE"] = file
+			}
+			code, stdout, stderr := runHydra(t, dir, env, append([]string{"backup"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("код завершения %d, ожидался %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantSummary) {
+				t.Errorf("в выводе нет %q:\n%s", tt.wantSummary, stdout)
+			}
+			if tt.toFile {
+				content := readFile(t, file)
+				if tt.wantCode == exitOK && (!strings.Contains(content, `"TOKEN": "t1"`) || !strings.Contains(content, `"PASSWORD": "p1"`)) {
+					t.Errorf("в копии нет секретов:\n%s", content)
+				}
+				if tt.wantCode != exitOK && content != "" {
+					t.Errorf("копия записана при ошибке:\n%s", content)
+				}
+				return
+			}
+			if got := sec.Paths("secret"); !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("секреты во вторичном Vault: %v, ожидалось %v", got, tt.wantPaths)
+			}
+			for _, path := range []string{"secret/app/db", "secret/app/api"} {
+				if got, want := sec.Get(path), primary.Get(path); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: %v, ожидалось %v", path, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,511 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Коды завершения Hydra
+const (
+	exitOK    = 0  // Команда выполнена успешно
+	exitError = 1  // Ошибка при выполнении команды
+	exitUsage = 2  // Неверные аргументы или не заданы обязательные настройки
+	exitDrift = 3  // hydra verify нашел расхождения между Vault
+	exitAuth  = 10 // Ошибка авторизации в Vault
+)
+
+// cliFlag связывает флаг команды с настройкой. Значение флага имеет наивысший приоритет
+type cliFlag struct {
+	Name    string
+	Setting string
+	Usage   string
+	Bool    bool
+}
+
+// cliCommand описывает команду hydra и ее флаги
+type cliCommand struct {
+	Name        string
+	Args        string // Позиционные аргументы для строки использования
+	Description string
+	Flags       []cliFlag
+}
+
+// Значения флагов, явно заданных в командной строке
+var flagValues = map[string]string{}
+
+// settingFlag - flag.Value, который записывает значение флага в flagValues
+type settingFlag struct {
+	setting string
+	isBool  bool
+}
+
+// String возвращает пустую строку: значение по умолчанию берется из настроек, а не из флага
+func (f *settingFlag) String() string {
+	return ""
+}
+
+func (f *settingFlag) Set(value string) error {
+	flagValues[f.setting] = value
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// Флаги авторизации, которые есть и у основного, и у вторичного Vault
+var vaultAuthFlags = []cliFlag{
+	{Name: "addr", Setting: "VAULT_ADDR", Usage: "URL Vault"},
+	{Name: "namespace", Setting: "VAULT_NAMESPACE", Usage: "Namespace Vault Enterprise"},
+	{Name: "auth-role", Setting: "VAULT_AUTH_ROLE", Usage: "Роль для JWT/K8S авторизации"},
+	{Name: "auth-url", Setting: "VAULT_AUTH_URL", Usage: "Путь для JWT/K8S авторизации, например auth/jwt/login"},
+	{Name: "role-id", Setting: "VAULT_ROLE_ID", Usage: "role_id для авторизации через AppRole"},
+	{Name: "secret-id-file", Setting: "VAULT_SECRET_ID_FILE", Usage: "Файл с secret_id для AppRole"},
+	{Name: "secret-id-wrapped", Setting: "VAULT_SECRET_ID_WRAPPED", Usage: "secret_id передан как wrapping токен", Bool: true},
+	{Name: "approle-path", Setting: "VAULT_APPROLE_PATH", Usage: "Путь монтирования AppRole"},
+	{Name: "cert-auth", Setting: "VAULT_CERT_AUTH", Usage: "Авторизация по клиентскому сертификату", Bool: true},
+	{Name: "cert-role", Setting: "VAULT_CERT_ROLE", Usage: "Роль cert auth"},
+	{Name: "cert-auth-path", Setting: "VAULT_CERT_AUTH_PATH", Usage: "Путь монтирования cert auth"},
+	{Name: "username", Setting: "VAULT_USERNAME", Usage: "Имя пользователя userpass/ldap"},
+	{Name: "password-file", Setting: "VAULT_PASSWORD_FILE", Usage: "Файл с паролем userpass/ldap"},
+	{Name: "login-method", Setting: "VAULT_LOGIN_METHOD", Usage: "Метод авторизации по паролю: userpass или ldap"},
+	{Name: "login-path", Setting: "VAULT_LOGIN_PATH", Usage: "Путь монтирования userpass/ldap"},
+}
+
+// Общие флаги TLS и логирования
+var commonFlags = []cliFlag{
+	{Name: "k8s-auth", Setting: "VAULT_K8S_AUTH", Usage: "Авторизация по токену service account Kubernetes", Bool: true},
+	{Name: "ca-path", Setting: "VAULT_CA_PATH", Usage: "Путь к сертификату CA"},
+	{Name: "client-cert", Setting: "VAULT_CLIENT_CERT", Usage: "Клиентский сертификат для mTLS"},
+	{Name: "client-key", Setting: "VAULT_CLIENT_KEY", Usage: "Ключ клиентского сертификата"},
+	{Name: "insecure", Setting: "VAULT_INSECURE", Usage: "Не проверять сертификат сервера", Bool: true},
+	{Name: "verbose", Setting: "VAULT_VERBOSE", Usage: "Уровень логирования: 1 - ERROR, 2 - INFO, 3 - DEBUG"},
+	{Name: "concurrency", Setting: "HYDRA_CONCURRENCY", Usage: "Число параллельных запросов к Vault"},
+	{Name: "rate-limit", Setting: "HYDRA_RATE_LIMIT", Usage: "Ограничение запросов в секунду к одному Vault, 0 - без ограничения"},
+	{Name: "retry-attempts", Setting: "HYDRA_RETRY_ATTEMPTS", Usage: "Число попыток запроса к Vault, GitLab и OpenShift, включая первую"},
+	{Name: "retry-wait", Setting: "HYDRA_RETRY_WAIT", Usage: "Пауза перед первым повтором, дальше удваивается"},
+	{Name: "retry-max-wait", Setting: "HYDRA_RETRY_MAX_WAIT", Usage: "Наибольшая пауза между повторами"},
+	{Name: "request-timeout", Setting: "HYDRA_REQUEST_TIMEOUT", Usage: "Таймаут одной попытки запроса"},
+}
+
+// secondaryFlags возвращает флаги вторичного Vault: --sec-addr и т.д. для настроек SEC_*
+func secondaryFlags(flags []cliFlag) []cliFlag {
+	result := make([]cliFlag, 0, len(flags))
+	for _, f := range flags {
+		result = append(result, cliFlag{
+			Name:    "sec-" + f.Name,
+			Setting: "SEC_" + f.Setting,
+			Usage:   f.Usage + " (вторичный Vault)",
+			Bool:    f.Bool,
+		})
+	}
+	return result
+}
+
+// joinFlags склеивает группы флагов в один список
+func joinFlags(groups ...[]cliFlag) []cliFlag {
+	var result []cliFlag
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	return result
+}
+
+var excludeFlag = cliFlag{Name: "exclude", Setting: "VAULT_EXCLUDE_REGEX", Usage: "Regex для исключения путей секретов"}
+var keyCollisionFlag = cliFlag{Name: "key-collision", Setting: "HYDRA_KEY_COLLISION", Usage: "Совпадение имен переменных из разных секретов: warn или error"}
+var secretsFileFlag = cliFlag{Name: "secrets-file", Setting: "HYDRA_SECRETS_FILE", Usage: "Файл секретов JSON или YAML вместо Vault"}
+var writePathFlag = cliFlag{Name: "write-path", Setting: "VAULT_WRITE_PATH", Usage: "Путь для записи ключей и токенов в Vault"}
+
+// Команды hydra. Секреты (токены, пароли, secret_id) флагами не принимаются,
+// чтобы они не попадали в историю команд и список процессов - для них есть переменные окружения и *-file флаги
+var commands = []*cliCommand{
+	{
+		Name:        "init",
+		Description: "Инициализация и разблокировка вторичного Vault и запись ключей в основной Vault",
+		Flags: joinFlags(vaultAuthFlags, secondaryFlags(vaultAuthFlags[:2]), commonFlags, []cliFlag{
+			writePathFlag,
+			{Name: "shares", Setting: "VAULT_INIT_SHARES", Usage: "Количество ключей разблокировки"},
+			{Name: "threshold", Setting: "VAULT_INIT_THRESHOLD", Usage: "Количество ключей, необходимых для разблокировки"},
+			{Name: "gitlab-api-url", Setting: "CI_API_V4_URL", Usage: "URL API GitLab"},
+			{Name: "gitlab-project-id", Setting: "CI_PROJECT_ID", Usage: "Идентификатор проекта GitLab"},
+		}),
+	},
+	{
+		Name:        "unseal",
+		Description: "Разблокировка вторичного Vault ключами SEC_VAULT_UNSEAL_KEY*",
+		Flags: joinFlags(secondaryFlags(vaultAuthFlags[:1]), commonFlags, []cliFlag{
+			writePathFlag,
+			{Name: "shares", Setting: "VAULT_INIT_SHARES", Usage: "Количество ключей разблокировки"},
+		}),
+	},
+	{
+		Name:        "inject",
+		Description: "Инъекция секретов из Vault в файл окружения",
+		Flags: joinFlags(vaultAuthFlags, commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_SECRET_PATH", Usage: "Пути секретов через пробел, путь@версия закрепляет версию, путь:ПРЕФИКС добавляет префикс к ключам"},
+			{Name: "recursive", Setting: "VAULT_RECURSIVE", Usage: "Рекурсивное чтение секретов", Bool: true},
+			excludeFlag,
+			keyCollisionFlag,
+			{Name: "files-path", Setting: "VAULT_FILES_PATH", Usage: "Директория для файловых секретов"},
+			{Name: "secrets-dir", Setting: "HYDRA_SECRETS_DIR", Usage: "Директория для файла переменных"},
+			{Name: "format", Setting: "HYDRA_OUTPUT_FORMAT", Usage: "Формат файла переменных: " + outputFormatNames()},
+			{Name: "project-dir", Setting: "CI_PROJECT_DIR", Usage: "Рабочая директория"},
+			secretsFileFlag,
+		}),
+	},
+	{
+		Name:        "exec",
+		Args:        "-- <команда> [аргументы]",
+		Description: "Запуск команды с секретами из Vault в переменных окружения, файловые ключи сохраняются во временную директорию $HYDRA_FILES_DIR",
+		Flags: joinFlags(vaultAuthFlags, commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_SECRET_PATH", Usage: "Пути секретов через пробел, путь@версия закрепляет версию, путь:ПРЕФИКС добавляет префикс к ключам"},
+			{Name: "recursive", Setting: "VAULT_RECURSIVE", Usage: "Рекурсивное чтение секретов", Bool: true},
+			excludeFlag,
+			keyCollisionFlag,
+			secretsFileFlag,
+		}),
+	},
+	{
+		Name:        "template",
+		Args:        "[source:destination[:mode] ...]",
+		Description: "Рендеринг файлов из шаблонов text/template с секретами из Vault, шаблоны также берутся из HYDRA_TEMPLATES и раздела templates файла конфигурации",
+		Flags:       joinFlags(vaultAuthFlags, commonFlags, []cliFlag{secretsFileFlag}),
+	},
+	{
+		Name:        "agent",
+		Description: "Постоянная работа: опрос версий секретов и перерисовка файлов inject и template только при изменениях",
+		Flags: joinFlags(vaultAuthFlags, commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_SECRET_PATH", Usage: "Пути секретов через пробел для файла переменных"},
+			{Name: "recursive", Setting: "VAULT_RECURSIVE", Usage: "Рекурсивное чтение секретов", Bool: true},
+			excludeFlag,
+			keyCollisionFlag,
+			{Name: "format", Setting: "HYDRA_OUTPUT_FORMAT", Usage: "Формат файла переменных: " + outputFormatNames()},
+			{Name: "templates", Setting: "HYDRA_TEMPLATES", Usage: "Шаблоны через пробел: source:destination[:mode]"},
+			{Name: "interval", Setting: "HYDRA_AGENT_INTERVAL", Usage: "Интервал опроса, например 30s или 5m"},
+			{Name: "command", Setting: "HYDRA_AGENT_COMMAND", Usage: "Команда, которая выполняется после изменения секретов"},
+			{Name: "signal", Setting: "HYDRA_AGENT_SIGNAL", Usage: "Сигнал процессу после изменения секретов"},
+			{Name: "pid", Setting: "HYDRA_AGENT_PID", Usage: "PID процесса для сигнала"},
+			{Name: "pid-file", Setting: "HYDRA_AGENT_PID_FILE", Usage: "Файл с PID процесса для сигнала"},
+			{Name: "health-addr", Setting: "HYDRA_AGENT_HEALTH_ADDR", Usage: "Адрес health endpoint, например :8080"},
+		}),
+	},
+	{
+		Name:        "versions",
+		Args:        "<путь>",
+		Description: "История версий секрета KV v2: время создания, удаление и уничтожение версий, custom_metadata",
+		Flags:       joinFlags(vaultAuthFlags, commonFlags),
+	},
+	{
+		Name:        "rollback",
+		Args:        "<путь> <версия>",
+		Description: "Откат секрета KV v2: данные указанной версии записываются как новая последняя версия",
+		Flags:       joinFlags(vaultAuthFlags, commonFlags),
+	},
+	{
+		Name:        "backup",
+		Description: "Рекурсивное копирование секретов из основного Vault во вторичный, по путям или по всем engine KV",
+		Flags: joinFlags(vaultAuthFlags, secondaryFlags(vaultAuthFlags), commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_BACKUP_PATH", Usage: "Пути для резервного копирования через пробел или * - все engine KV"},
+			excludeFlag,
+			{Name: "mode", Setting: "HYDRA_BACKUP_MODE", Usage: "Режим копирования: full (engine пересоздается) или incremental (только изменения)"},
+			{Name: "prune", Setting: "HYDRA_BACKUP_PRUNE", Usage: "Удалять из копии секреты, которых нет в источнике (incremental)", Bool: true},
+			{Name: "staging", Setting: "HYDRA_BACKUP_STAGING", Usage: "Писать копию в staging engine и заменять им копию после успешного запуска (incremental)", Bool: true},
+			{Name: "history", Setting: "HYDRA_BACKUP_HISTORY", Usage: "Копировать все версии секретов KV v2, их метаданные и настройки engine источника", Bool: true},
+			secretsFileFlag,
+			secondaryFlags([]cliFlag{secretsFileFlag})[0],
+		}),
+	},
+	{
+		Name:        "verify",
+		Description: "Сверка секретов основного и вторичного Vault по хешу данных: отсутствующие, лишние и отличающиеся пути",
+		Flags: joinFlags(vaultAuthFlags, secondaryFlags(vaultAuthFlags), commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_BACKUP_PATH", Usage: "Пути для сверки через пробел или * - все engine KV"},
+			excludeFlag,
+			{Name: "format", Setting: "HYDRA_VERIFY_FORMAT", Usage: "Формат отчета в stdout: text или json"},
+			{Name: "report", Setting: "HYDRA_VERIFY_REPORT", Usage: "Файл для отчета в JSON"},
+			secretsFileFlag,
+			secondaryFlags([]cliFlag{secretsFileFlag})[0],
+		}),
+	},
+	{
+		Name:        "export",
+		Args:        "[файл]",
+		Description: "Экспорт секретов из VAULT_BACKUP_PATH с метаданными KV v2 в локальный архив, зашифрованный age или паролем",
+		Flags: joinFlags(vaultAuthFlags, commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_BACKUP_PATH", Usage: "Путь для резервного копирования"},
+			excludeFlag,
+			{Name: "file", Setting: "HYDRA_ARCHIVE_FILE", Usage: "Файл архива, по умолчанию hydra-backup-<дата>.tar.gz.age"},
+			{Name: "recipients", Setting: "HYDRA_AGE_RECIPIENTS", Usage: "Публичные ключи age через пробел"},
+			{Name: "recipients-file", Setting: "HYDRA_AGE_RECIPIENTS_FILE", Usage: "Файл с публичными ключами age"},
+			{Name: "passphrase-file", Setting: "HYDRA_ARCHIVE_PASSPHRASE_FILE", Usage: "Файл с паролем архива"},
+		}),
+	},
+	{
+		Name:        "restore",
+		Args:        "[архив]",
+		Description: "Восстановление секретов в основной Vault из архива hydra export или из вторичного Vault по VAULT_BACKUP_PATH",
+		Flags: joinFlags(vaultAuthFlags, secondaryFlags(vaultAuthFlags), commonFlags, []cliFlag{
+			{Name: "path", Setting: "VAULT_BACKUP_PATH", Usage: "Пути во вторичном Vault, если архив не указан"},
+			excludeFlag,
+			{Name: "file", Setting: "HYDRA_ARCHIVE_FILE", Usage: "Файл архива"},
+			{Name: "identity-file", Setting: "HYDRA_AGE_IDENTITY_FILE", Usage: "Файл с приватными ключами age"},
+			{Name: "passphrase-file", Setting: "HYDRA_ARCHIVE_PASSPHRASE_FILE", Usage: "Файл с паролем архива"},
+			{Name: "prefix", Setting: "HYDRA_RESTORE_PREFIX", Usage: "Новый префикс путей или правила старый=новый через пробел"},
+			{Name: "conflict", Setting: "HYDRA_RESTORE_CONFLICT", Usage: "Если секрет уже есть: skip, overwrite или new-version"},
+			{Name: "dry-run", Setting: "HYDRA_RESTORE_DRY_RUN", Usage: "Только показать, что будет создано или изменено", Bool: true},
+		}),
+	},
+	{
+		Name:        "okd-sync",
+		Description: "Запись токенов service account OpenShift в Vault",
+		Flags: joinFlags(vaultAuthFlags, commonFlags, []cliFlag{
+			{Name: "oc-username", Setting: "OC_USERNAME", Usage: "Имя пользователя OpenShift"},
+			{Name: "oc-namespaces", Setting: "OC_NAMESPACES", Usage: "Namespace OpenShift через запятую"},
+			{Name: "oc-cluster", Setting: "OC_CLUSTER", Usage: "Имя кластера OpenShift"},
+			writePathFlag,
+			excludeFlag,
+		}),
+	},
+	{
+		Name:        "config",
+		Args:        "show",
+		Description: "Вывод итоговых настроек с указанием источника каждого значения",
+	},
+	{
+		Name:        "completion",
+		Args:        "bash|zsh|fish",
+		Description: "Вывод скрипта автодополнения для shell",
+	},
+	{
+		Name:        "help",
+		Args:        "[команда]",
+		Description: "Вывод справки",
+	},
+}
+
+// findCommand ищет команду по имени
+func findCommand(name string) *cliCommand {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// lookupCommand - findCommand для extractConfigArgs. Прямая ссылка из загрузки конфигурации на commands
+// образует цикл инициализации (commands -> ... -> setting -> loadConfig), поэтому функция задается в init
+var lookupCommand func(name string) *cliCommand
+
+func init() {
+	lookupCommand = findCommand
+}
+
+// takesValue сообщает, что у команды есть флаг name со значением (не логический)
+func (cmd *cliCommand) takesValue(name string) bool {
+	if cmd == nil {
+		return false
+	}
+	for _, f := range cmd.Flags {
+		if f.Name == name {
+			return !f.Bool
+		}
+	}
+	return false
+}
+
+// flagSet создает набор флагов команды
+func (cmd *cliCommand) flagSet(output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	for _, f := range cmd.Flags {
+		fs.Var(&settingFlag{setting: f.Setting, isBool: f.Bool}, f.Name, fmt.Sprintf("%s (%s)", f.Usage, f.Setting))
+	}
+	fs.Usage = func() { cmd.printUsage(fs) }
+	return fs
+}
+
+// printUsage выводит справку по команде
+func (cmd *cliCommand) printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintf(out, "Использование: hydra %s", cmd.Name)
+	if len(cmd.Flags) > 0 {
+		fmt.Fprint(out, " [флаги]")
+	}
+	if cmd.Args != "" {
+		fmt.Fprintf(out, " %s", cmd.Args)
+	}
+	fmt.Fprintf(out, "\n\n%s\n", cmd.Description)
+	if len(cmd.Flags) > 0 {
+		fmt.Fprintln(out, "\nФлаги:")
+		fs.PrintDefaults()
+	}
+	fmt.Fprintln(out, "\nГлобальные флаги:")
+	fmt.Fprintln(out, "  --config string\n    \tФайл конфигурации (HYDRA_CONFIG)")
+	fmt.Fprintln(out, "  --profile string\n    \tПрофили из файла конфигурации через запятую (HYDRA_PROFILE)")
+	fmt.Fprintln(out, "\nФлаг имеет приоритет над переменной окружения, переменная окружения - над файлом конфигурации.")
+}
+
+// parseCommandLine разбирает команду и ее флаги.
+// При ошибке в аргументах завершает работу с кодом 2, при --help - с кодом 0
+func parseCommandLine() (*cliCommand, []string) {
+	// --config и --profile глобальные, их вырезает из os.Args загрузка файла конфигурации
+	configOnce.Do(loadConfig)
+	args := os.Args[1:]
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		exit(exitUsage)
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		exit(exitOK)
+	case "-version", "--version":
+		fmt.Println(version)
+		exit(exitOK)
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		Log(Error, fmt.Sprintf("Неизвестная команда '%s', список команд: hydra help", args[0]))
+		exit(exitUsage)
+	}
+	// Ошибку разбора flag пишет в stderr, справка выводится здесь: по --help в stdout, после ошибки в stderr
+	fs := cmd.flagSet(os.Stderr)
+	fs.Usage = func() {}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stdout)
+			cmd.printUsage(fs)
+			exit(exitOK)
+		}
+		cmd.printUsage(fs)
+		exit(exitUsage)
+	}
+	return cmd, fs.Args()
+}
+
+// usageError выводит ошибку и справку по команде, затем завершает работу с кодом 2
+func usageError(cmd *cliCommand, message string) {
+	Log(Error, message)
+	cmd.printUsage(cmd.flagSet(os.Stderr))
+	exit(exitUsage)
+}
+
+// printHelp выводит общую справку или справку по команде
+func printHelp(args []string) {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		Log(Error, fmt.Sprintf("Неизвестная команда '%s'", args[0]))
+		exit(exitUsage)
+	}
+	cmd.printUsage(cmd.flagSet(os.Stdout))
+}
+
+// printCompletion выводит скрипт автодополнения для bash, zsh или fish
+func printCompletion(cmd *cliCommand, args []string) {
+	if len(args) != 1 {
+		usageError(cmd, "Ожидается тип shell: bash, zsh или fish")
+	}
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Println("_hydra_completion() {")
+		fmt.Println("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+		fmt.Println("    if [ \"$COMP_CWORD\" -eq 1 ]; then")
+		fmt.Printf("        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(names, " "))
+		fmt.Println("        return")
+		fmt.Println("    fi")
+		fmt.Println("    case \"${COMP_WORDS[1]}\" in")
+		for _, c := range commands {
+			fmt.Printf("        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.Name, strings.Join(c.completionWords(), " "))
+		}
+		fmt.Println("    esac")
+		fmt.Println("}")
+		fmt.Println("complete -F _hydra_completion hydra")
+	case "zsh":
+		fmt.Println("#compdef hydra")
+		fmt.Println("_hydra() {")
+		fmt.Println("    if (( CURRENT == 2 )); then")
+		fmt.Println("        local -a commands")
+		fmt.Println("        commands=(")
+		for _, c := range commands {
+			fmt.Printf("            '%s:%s'\n", c.Name, zshEscape(c.Description))
+		}
+		fmt.Println("        )")
+		fmt.Println("        _describe 'command' commands")
+		fmt.Println("        return")
+		fmt.Println("    fi")
+		fmt.Println("    case $words[2] in")
+		for _, c := range commands {
+			fmt.Printf("        %s) compadd -- %s ;;\n", c.Name, strings.Join(c.completionWords(), " "))
+		}
+		fmt.Println("    esac")
+		fmt.Println("}")
+		fmt.Println("compdef _hydra hydra")
+	case "fish":
+		fmt.Println("complete -c hydra -f")
+		for _, c := range commands {
+			fmt.Printf("complete -c hydra -n __fish_use_subcommand -a %s -d '%s'\n", c.Name, fishEscape(c.Description))
+			for _, f := range c.Flags {
+				fmt.Printf("complete -c hydra -n '__fish_seen_subcommand_from %s' -l %s -d '%s'\n", c.Name, f.Name, fishEscape(f.Usage))
+			}
+			for _, arg := range c.argWords() {
+				fmt.Printf("complete -c hydra -n '__fish_seen_subcommand_from %s' -a %s\n", c.Name, arg)
+			}
+		}
+	default:
+		usageError(cmd, fmt.Sprintf("Неизвестный shell '%s', ожидается bash, zsh или fish", args[0]))
+	}
+}
+
+// completionWords возвращает флаги и позиционные аргументы команды для автодополнения
+func (cmd *cliCommand) completionWords() []string {
+	words := cmd.argWords()
+	for _, f := range cmd.Flags {
+		words = append(words, "--"+f.Name)
+	}
+	return words
+}
+
+// argWords возвращает фиксированные значения позиционных аргументов команды
+func (cmd *cliCommand) argWords() []string {
+	switch cmd.Name {
+	case "help":
+		var names []string
+		for _, c := range commands {
+			names = append(names, c.Name)
+		}
+		return names
+	case "config", "completion":
+		return strings.Split(cmd.Args, "|")
+	}
+	return nil
+}
+
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", "'\\''", ":", "\\:").Replace(s)
+}
+
+func fishEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "\\'")
+}
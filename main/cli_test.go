@@ -0,0 +1,51 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUsageOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string // справка ожидается в stdout, иначе в stderr
+		wantStderr string
+	}{
+		{name: "без команды", wantCode: exitUsage, wantStderr: "Использование: ./hydra"},
+		{name: "--help", args: []string{"--help"}, wantStdout: "Использование: ./hydra"},
+		{name: "help", args: []string{"help"}, wantStdout: "Использование: ./hydra"},
+		{name: "help команды", args: []string{"help", "inject"}, wantStdout: "Использование: hydra inject"},
+		{name: "--help команды", args: []string{"inject", "--help"}, wantStdout: "Использование: hydra inject"},
+		{name: "неизвестный флаг", args: []string{"inject", "--unknown"}, wantCode: exitUsage, wantStderr: "Использование: hydra inject"},
+		{name: "лишний аргумент", args: []string{"config", "list"}, wantCode: exitUsage, wantStderr: "Использование: hydra config"},
+		{name: "--version", args: []string{"--version"}, wantStdout: version},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runHydra(t, t.TempDir(), nil, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("код завершения %d, ожидался %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			if tt.wantStdout != "" && !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("в stdout нет %q:\n%s", tt.wantStdout, stdout)
+			}
+			if tt.wantStderr != "" {
+				if !strings.Contains(stderr, tt.wantStderr) {
+					t.Errorf("в stderr нет %q:\n%s", tt.wantStderr, stderr)
+				}
+				if stdout != "" {
+					t.Errorf("после ошибки в stdout выведено:\n%s", stdout)
+				}
+			}
+		})
+	}
+}
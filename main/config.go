@@ -0,0 +1,440 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Имя файла конфигурации, который ищется в CI_PROJECT_DIR (или в текущей директории)
+const defaultConfigFile = ".hydra.yaml"
+
+// settingInfo описывает одну настройку Hydra. Имя настройки совпадает с именем переменной окружения
+type settingInfo struct {
+	Name      string
+	Default   string
+	Secret    bool   // Значение скрывается в hydra config show
+	Separator string // Разделитель, которым склеивается список из файла конфигурации
+}
+
+// Все известные настройки в порядке вывода hydra config show
+var knownSettings = []settingInfo{
+	{Name: "VAULT_ADDR"},
+	{Name: "VAULT_NAMESPACE"},
+	{Name: "VAULT_TOKEN", Secret: true},
+	{Name: "VAULT_AUTH_ROLE"},
+	{Name: "VAULT_AUTH_URL"},
+	{Name: "VAULT_ID_TOKEN", Secret: true},
+	{Name: "VAULT_K8S_AUTH", Default: "false"},
+	{Name: "VAULT_ROLE_ID"},
+	{Name: "VAULT_SECRET_ID", Secret: true},
+	{Name: "VAULT_SECRET_ID_FILE"},
+	{Name: "VAULT_SECRET_ID_WRAPPED", Default: "false"},
+	{Name: "VAULT_APPROLE_PATH", Default: "approle"},
+	{Name: "VAULT_CERT_AUTH", Default: "false"},
+	{Name: "VAULT_CERT_ROLE"},
+	{Name: "VAULT_CERT_AUTH_PATH", Default: "cert"},
+	{Name: "VAULT_USERNAME"},
+	{Name: "VAULT_PASSWORD", Secret: true},
+	{Name: "VAULT_PASSWORD_FILE"},
+	{Name: "VAULT_LOGIN_METHOD", Default: "userpass"},
+	{Name: "VAULT_LOGIN_PATH"},
+	{Name: "SEC_VAULT_ADDR"},
+	{Name: "SEC_VAULT_NAMESPACE"},
+	{Name: "SEC_VAULT_TOKEN", Secret: true},
+	{Name: "SEC_VAULT_AUTH_ROLE"},
+	{Name: "SEC_VAULT_AUTH_URL"},
+	{Name: "SEC_VAULT_ROLE_ID"},
+	{Name: "SEC_VAULT_SECRET_ID", Secret: true},
+	{Name: "SEC_VAULT_SECRET_ID_FILE"},
+	{Name: "SEC_VAULT_SECRET_ID_WRAPPED", Default: "false"},
+	{Name: "SEC_VAULT_APPROLE_PATH", Default: "approle"},
+	{Name: "SEC_VAULT_CERT_AUTH", Default: "false"},
+	{Name: "SEC_VAULT_CERT_ROLE"},
+	{Name: "SEC_VAULT_CERT_AUTH_PATH", Default: "cert"},
+	{Name: "SEC_VAULT_USERNAME"},
+	{Name: "SEC_VAULT_PASSWORD", Secret: true},
+	{Name: "SEC_VAULT_PASSWORD_FILE"},
+	{Name: "SEC_VAULT_LOGIN_METHOD", Default: "userpass"},
+	{Name: "SEC_VAULT_LOGIN_PATH"},
+	{Name: "VAULT_CA_PATH"},
+	{Name: "VAULT_CLIENT_CERT"},
+	{Name: "VAULT_CLIENT_KEY"},
+	{Name: "VAULT_INSECURE", Default: "false"},
+	{Name: "VAULT_SECRET_PATH", Separator: " "},
+	{Name: "VAULT_RECURSIVE", Default: "false"},
+	{Name: "VAULT_FILES_PATH", Default: "vault_files"},
+	{Name: "HYDRA_SECRETS_DIR", Default: "tmp"},
+	{Name: "HYDRA_OUTPUT_FORMAT", Default: defaultOutputFormat},
+	{Name: "HYDRA_KEY_COLLISION", Default: collisionWarn},
+	{Name: "HYDRA_TEMPLATES", Separator: " "},
+	{Name: "HYDRA_ARCHIVE_FILE"},
+	{Name: "HYDRA_AGE_RECIPIENTS", Separator: " "},
+	{Name: "HYDRA_AGE_RECIPIENTS_FILE"},
+	{Name: "HYDRA_ARCHIVE_PASSPHRASE", Secret: true},
+	{Name: "HYDRA_ARCHIVE_PASSPHRASE_FILE"},
+	{Name: "HYDRA_AGE_IDENTITY_FILE"},
+	{Name: "HYDRA_RESTORE_PREFIX", Separator: " "},
+	{Name: "HYDRA_RESTORE_CONFLICT", Default: "skip"},
+	{Name: "HYDRA_RESTORE_DRY_RUN"},
+	{Name: "HYDRA_BACKUP_MODE", Default: backupModeFull},
+	{Name: "HYDRA_BACKUP_PRUNE"},
+	{Name: "HYDRA_BACKUP_STAGING"},
+	{Name: "HYDRA_BACKUP_HISTORY"},
+	{Name: "HYDRA_VERIFY_FORMAT", Default: verifyFormatText},
+	{Name: "HYDRA_VERIFY_REPORT"},
+	{Name: "HYDRA_SECRETS_FILE"},
+	{Name: "SEC_HYDRA_SECRETS_FILE"},
+	{Name: "HYDRA_CONCURRENCY", Default: "4"},
+	{Name: "HYDRA_RATE_LIMIT"},
+	{Name: "HYDRA_RETRY_ATTEMPTS", Default: "5"},
+	{Name: "HYDRA_RETRY_WAIT", Default: "500ms"},
+	{Name: "HYDRA_RETRY_MAX_WAIT", Default: "30s"},
+	{Name: "HYDRA_REQUEST_TIMEOUT", Default: "60s"},
+	{Name: "HYDRA_AGENT_INTERVAL", Default: "60s"},
+	{Name: "HYDRA_AGENT_COMMAND"},
+	{Name: "HYDRA_AGENT_SIGNAL", Default: "HUP"},
+	{Name: "HYDRA_AGENT_PID"},
+	{Name: "HYDRA_AGENT_PID_FILE"},
+	{Name: "HYDRA_AGENT_HEALTH_ADDR"},
+	{Name: "HYDRA_DOTENV_MAX_VARIABLES", Default: "20"},
+	{Name: "HYDRA_DOTENV_MAX_SIZE", Default: "5120"},
+	{Name: "VAULT_EXCLUDE_REGEX"},
+	{Name: "VAULT_WRITE_PATH"},
+	{Name: "VAULT_BACKUP_PATH", Separator: " "},
+	{Name: "VAULT_INIT_SHARES", Default: "5"},
+	{Name: "VAULT_INIT_THRESHOLD", Default: "3"},
+	{Name: "VAULT_VERBOSE", Default: "1"},
+	{Name: "OC_USERNAME"},
+	{Name: "OC_PASSWORD", Secret: true},
+	{Name: "OC_NAMESPACES", Separator: ","},
+	{Name: "OC_CLUSTER"},
+	{Name: "CI_PROJECT_DIR"},
+	{Name: "CI_API_V4_URL"},
+	{Name: "CI_PROJECT_ID"},
+	{Name: "CI_PROJECT_NAMESPACE_ID"},
+	{Name: "GITLAB_API_TOKEN", Secret: true},
+}
+
+// configFile - структура файла конфигурации
+//
+//	profile: prod              # профиль по умолчанию
+//	settings:                  # общие настройки для всех профилей
+//	  VAULT_VERBOSE: 2
+//	profiles:
+//	  prod:
+//	    VAULT_ADDR: https://vault.prod.mydomain.ru
+//	  okd-east:
+//	    OC_CLUSTER: east
+//	    OC_NAMESPACES: [ns1, ns2]
+//	keys:                      # правила для ключей секретов, см. keyRule
+//	  myns/app/db:
+//	    prefix: DB_
+//	templates:                 # шаблоны для hydra template, см. templateSpec
+//	  - source: application.yaml.tmpl
+//	    destination: config/application.yaml
+type configFile struct {
+	Profile   string                            `yaml:"profile"`
+	Settings  map[string]interface{}            `yaml:"settings"`
+	Profiles  map[string]map[string]interface{} `yaml:"profiles"`
+	Keys      map[string]keyRule                `yaml:"keys"`
+	Templates []templateSpec                    `yaml:"templates"`
+}
+
+var (
+	configOnce     sync.Once
+	configPath     string            // Путь к загруженному файлу конфигурации
+	configProfiles []string          // Выбранные профили
+	configValues   map[string]string // Значения из файла после слияния профилей
+	configOrigins  map[string]string // Раздел файла, из которого взято значение
+	configUnknown  []string          // Неизвестные настройки в файле, скорее всего опечатки
+	configErr      error             // Ошибка загрузки, выводится в main
+)
+
+// setting возвращает значение настройки с учетом порядка приоритетов:
+// флаг командной строки > переменная окружения > профиль из файла конфигурации > общие настройки файла > значение по умолчанию
+func setting(name string) string {
+	value, _ := resolveSetting(name)
+	return value
+}
+
+// resolveSetting возвращает значение настройки и его источник
+func resolveSetting(name string) (string, string) {
+	configOnce.Do(loadConfig)
+
+	if value, ok := flagValues[name]; ok {
+		return value, "flag"
+	}
+	if value := os.Getenv(name); value != "" {
+		return value, "env"
+	}
+	if value, ok := configValues[name]; ok {
+		return value, configOrigins[name]
+	}
+	for _, info := range knownSettings {
+		if info.Name == name && info.Default != "" {
+			return info.Default, "default"
+		}
+	}
+	return "", ""
+}
+
+// loadConfig находит и читает файл конфигурации.
+// Вызывается при инициализации глобальных переменных, поэтому не должен использовать Log и setting
+func loadConfig() {
+	configValues = make(map[string]string)
+	configOrigins = make(map[string]string)
+
+	path, profiles := extractConfigArgs()
+	explicit := path != ""
+	if path == "" {
+		path = os.Getenv("HYDRA_CONFIG")
+		explicit = path != ""
+	}
+	if path == "" {
+		path = filepath.Join(os.Getenv("CI_PROJECT_DIR"), defaultConfigFile)
+	}
+	if profiles == "" {
+		profiles = os.Getenv("HYDRA_PROFILE")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			configErr = fmt.Errorf("не удалось прочитать файл конфигурации %s: %v", path, err)
+		}
+		return
+	}
+	var config configFile
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		configErr = fmt.Errorf("не удалось разобрать файл конфигурации %s: %v", path, err)
+		return
+	}
+	configPath = path
+	configTemplates = config.Templates
+	configKeyRules = make(map[string]keyRule, len(config.Keys))
+	for secretPath, rule := range config.Keys {
+		configKeyRules[normalizeSecretPath(secretPath)] = rule
+	}
+
+	if err := mergeConfigSection(config.Settings, "file: settings"); err != nil {
+		configErr = fmt.Errorf("%s: %v", path, err)
+		return
+	}
+
+	if profiles == "" {
+		profiles = config.Profile
+	}
+	if profiles == "" {
+		return
+	}
+	// Можно указать несколько профилей через запятую, например профиль Vault и профиль OKD.
+	// Значения из профиля правее перекрывают значения из профиля левее
+	for _, profile := range splitStringToList(profiles, ",") {
+		section, ok := config.Profiles[profile]
+		if !ok {
+			configErr = fmt.Errorf("профиль '%s' не найден в файле конфигурации %s", profile, path)
+			return
+		}
+		if err := mergeConfigSection(section, "file: profile "+profile); err != nil {
+			configErr = fmt.Errorf("%s: %v", path, err)
+			return
+		}
+		configProfiles = append(configProfiles, profile)
+	}
+}
+
+// mergeConfigSection добавляет значения раздела файла конфигурации, перекрывая уже загруженные
+func mergeConfigSection(section map[string]interface{}, origin string) error {
+	for key, raw := range section {
+		name := strings.ToUpper(key)
+		value, err := configValueToString(name, raw)
+		if err != nil {
+			return err
+		}
+		if !isKnownSetting(name) {
+			configUnknown = append(configUnknown, name)
+		}
+		configValues[name] = value
+		configOrigins[name] = origin
+	}
+	return nil
+}
+
+// isKnownSetting проверяет, что настройка известна Hydra
+func isKnownSetting(name string) bool {
+	if strings.HasPrefix(name, "SEC_VAULT_UNSEAL_KEY") {
+		return true
+	}
+	for _, info := range knownSettings {
+		if info.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfig сообщает об ошибках загрузки файла конфигурации
+func checkConfig() {
+	configOnce.Do(loadConfig)
+	if configErr != nil {
+		HandleError(configErr, "Ошибка в файле конфигурации", Error)
+	}
+	if configPath != "" {
+		Log(Debug, fmt.Sprintf("Загружен файл конфигурации %s, профили: %v", configPath, configProfiles))
+	}
+	for _, name := range configUnknown {
+		Log(Error, fmt.Sprintf("Неизвестная настройка %s в файле конфигурации %s", name, configPath))
+	}
+}
+
+// configValueToString приводит значение из YAML к строке, как если бы оно было задано переменной окружения
+func configValueToString(name string, raw interface{}) (string, error) {
+	switch value := raw.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		separator := " "
+		for _, info := range knownSettings {
+			if info.Name == name && info.Separator != "" {
+				separator = info.Separator
+			}
+		}
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			itemStr, err := configValueToString(name, item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, itemStr)
+		}
+		return strings.Join(items, separator), nil
+	case map[string]interface{}:
+		return "", fmt.Errorf("значение %s должно быть строкой, числом, логическим значением или списком", name)
+	default:
+		return fmt.Sprintf("%v", value), nil
+	}
+}
+
+// extractConfigArgs извлекает из аргументов командной строки --config и --profile
+// и удаляет их, чтобы остальной разбор аргументов их не видел. Как и разбор флагов команды,
+// поиск останавливается на -- и на первом аргументе команды: флаги после них относятся
+// к команде, которую запускает hydra exec, или к аргументам самой команды
+func extractConfigArgs() (string, string) {
+	var path, profiles string
+	var cmd *cliCommand
+	command := false
+	args := []string{os.Args[0]}
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if arg == "--" || (command && !isFlagArg(arg)) {
+			args = append(args, os.Args[i:]...)
+			break
+		}
+		if !isFlagArg(arg) {
+			command = true
+			if lookupCommand != nil {
+				cmd = lookupCommand(arg)
+			}
+			args = append(args, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "config" && name != "profile" {
+			args = append(args, arg)
+			// Значение флага команды пропускаем, чтобы не принять его за первый аргумент
+			if !hasValue && cmd.takesValue(name) && i+1 < len(os.Args) {
+				i++
+				args = append(args, os.Args[i])
+			}
+			continue
+		}
+		if !hasValue && i+1 < len(os.Args) {
+			i++
+			value = os.Args[i]
+		}
+		if name == "config" {
+			path = value
+		} else {
+			profiles = value
+		}
+	}
+	os.Args = args
+	return path, profiles
+}
+
+// isFlagArg сообщает, что аргумент - флаг. Одиночный "-" флагом не считается, как и в пакете flag
+func isFlagArg(arg string) bool {
+	return strings.HasPrefix(arg, "-") && arg != "-"
+}
+
+// redactSetting скрывает значения секретных настроек
+func redactSetting(name, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, info := range knownSettings {
+		if info.Name == name && info.Secret {
+			return "***"
+		}
+	}
+	if strings.HasPrefix(name, "SEC_VAULT_UNSEAL_KEY") {
+		return "***"
+	}
+	return value
+}
+
+// showConfig выводит итоговые настройки (секреты скрыты) и источник каждого значения
+func showConfig() {
+	if configPath != "" {
+		fmt.Printf("# Файл конфигурации: %s\n", configPath)
+		if len(configProfiles) > 0 {
+			fmt.Printf("# Профили: %s\n", strings.Join(configProfiles, ", "))
+		}
+	} else {
+		fmt.Println("# Файл конфигурации не найден, используются только переменные окружения")
+	}
+	fmt.Println("# Приоритет: флаг > переменная окружения > профиль > settings > значение по умолчанию")
+
+	names := make([]string, 0, len(knownSettings))
+	for _, info := range knownSettings {
+		names = append(names, info.Name)
+	}
+	// Ключи разблокировки не известны заранее, выводим заданные
+	var unsealKeys []string
+	for name := range configValues {
+		if strings.HasPrefix(name, "SEC_VAULT_UNSEAL_KEY") {
+			unsealKeys = append(unsealKeys, name)
+		}
+	}
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, "SEC_VAULT_UNSEAL_KEY") {
+			if _, ok := configValues[name]; !ok {
+				unsealKeys = append(unsealKeys, name)
+			}
+		}
+	}
+	sort.Strings(unsealKeys)
+	names = append(names, unsealKeys...)
+
+	for _, name := range names {
+		value, source := resolveSetting(name)
+		if source == "" {
+			source = "-"
+		}
+		fmt.Printf("%-28s = %-40s # %s\n", name, redactSetting(name, value), source)
+	}
+}
@@ -0,0 +1,70 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractConfigArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         string
+		wantPath     string
+		wantProfiles string
+		wantArgs     string
+	}{
+		{
+			name:     "до команды",
+			args:     "hydra --config hydra.yaml inject",
+			wantPath: "hydra.yaml",
+			wantArgs: "hydra inject",
+		},
+		{
+			name:         "среди флагов команды",
+			args:         "hydra inject --profile=prod --path secret/app --config hydra.yaml --recursive",
+			wantPath:     "hydra.yaml",
+			wantProfiles: "prod",
+			wantArgs:     "hydra inject --path secret/app --recursive",
+		},
+		{
+			name:         "после команды",
+			args:         "hydra config --profile=prod --config hydra.yaml show",
+			wantPath:     "hydra.yaml",
+			wantProfiles: "prod",
+			wantArgs:     "hydra config show",
+		},
+		{
+			name:     "флаг после --",
+			args:     "hydra config --config hydra.yaml -- show --config foo.yaml",
+			wantPath: "hydra.yaml",
+			wantArgs: "hydra config -- show --config foo.yaml",
+		},
+		{
+			name:     "флаг после аргумента команды",
+			args:     "hydra config show --config foo.yaml --profile dev",
+			wantArgs: "hydra config show --config foo.yaml --profile dev",
+		},
+	}
+	defer func(previous []string) { os.Args = previous }(os.Args)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = strings.Fields(tt.args)
+			path, profiles := extractConfigArgs()
+			if path != tt.wantPath || profiles != tt.wantProfiles {
+				t.Errorf("config %q, profile %q, ожидается %q, %q", path, profiles, tt.wantPath, tt.wantProfiles)
+			}
+			if want := strings.Fields(tt.wantArgs); !reflect.DeepEqual(os.Args, want) {
+				t.Errorf("аргументы %q, ожидается %q", os.Args, want)
+			}
+		})
+	}
+}
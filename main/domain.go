@@ -11,7 +11,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
 const contributeUrl = "https://github.com/kr1ptonec/hydra" // My contribute Url
@@ -20,7 +19,7 @@ const RootCertPathNexus = "https://mycertpath/root.crt"    // Your static path t
 
 // RootCertContent читает содержимое сертификата с указанного пути в Nexus сохранения на диск.
 func RootCertContent() (string, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := currentRetryPolicy().HTTPClient(nil, hydraLog)
 
 	content, err := fetchCertFromURL(client, RootCertPathNexus)
 	if err == nil {
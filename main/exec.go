@@ -0,0 +1,163 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hydra/pkg/hydra"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// Код завершения, если команду не удалось запустить (как в shell)
+const exitCommandNotFound = 127
+
+// Сигналы, которые hydra exec пересылает дочернему процессу
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// execCommand запускает команду с секретами из VAULT_SECRET_PATH в переменных окружения.
+// Секреты не пишутся в файл окружения, файловые ключи сохраняются во временную директорию,
+// которая удаляется после завершения команды. Возвращает код завершения команды
+func execCommand(args []string) int {
+	client := secretStore(primaryConfig, secretsFile)
+
+	filesDir, err := os.MkdirTemp("", "hydra-files-")
+	if err != nil {
+		Log(Error, fmt.Sprintf("Не удалось создать временную директорию для файлов: %s", err))
+		return exitError
+	}
+	defer func() {
+		if err := os.RemoveAll(filesDir); err != nil {
+			Log(Error, fmt.Sprintf("Не удалось удалить временную директорию %s: %s", filesDir, err))
+			return
+		}
+		Log(Debug, fmt.Sprintf("Временная директория %s удалена", filesDir))
+	}()
+
+	// Сигналы подписываем до записи файловых секретов: иначе SIGTERM во время чтения секретов
+	// завершит процесс без удаления временной директории. Подписка держится до завершения команды,
+	// чтобы не потерять Ctrl+C между стартом и ожиданием
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	secrets, err := collectExecSecrets(client, filesDir)
+	if err != nil {
+		Log(Error, fmt.Sprintf("Ошибка при получении секретов:\n %s", err))
+		return exitError
+	}
+	// Сигнал, пришедший во время чтения секретов, отменяет запуск команды
+	select {
+	case sig := <-signals:
+		Log(Info, fmt.Sprintf("Получен сигнал %s, команда %s не запускается", sig, args[0]))
+		return signalExitCode(sig)
+	default:
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	for key, value := range secrets {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	// Путь к файловым секретам, чтобы команда могла их найти
+	cmd.Env = append(cmd.Env, "HYDRA_FILES_DIR="+filesDir)
+
+	Log(Info, fmt.Sprintf("Запускаем %s с %d переменными из Vault", args[0], len(secrets)))
+	if err := cmd.Start(); err != nil {
+		Log(Error, fmt.Sprintf("Не удалось запустить команду %s: %s", args[0], err))
+		return exitCommandNotFound
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for {
+		select {
+		case sig := <-signals:
+			Log(Debug, fmt.Sprintf("Пересылаем сигнал %s процессу %d", sig, cmd.Process.Pid))
+			if err := cmd.Process.Signal(sig); err != nil {
+				Log(Error, fmt.Sprintf("Не удалось переслать сигнал %s: %s", sig, err))
+			}
+		case err := <-done:
+			return childExitCode(err)
+		}
+	}
+}
+
+// collectExecSecrets собирает секреты со всех путей VAULT_SECRET_PATH в одну карту.
+// В рекурсивном режиме обходятся все вложенные секреты
+func collectExecSecrets(client hydra.SecretStore, filesDir string) (map[string]string, error) {
+	paths := strings.Split(vaultSecretPaths, " ")
+	if checkVaultRecursiveEnv() {
+		var recursivePaths []string
+		for _, spec := range paths {
+			path, prefix := splitPathSpec(spec)
+			found, _ := listAllPaths(client, path)
+			for _, recursivePath := range found {
+				recursivePaths = append(recursivePaths, joinPathSpec(recursivePath, prefix))
+			}
+		}
+		paths = recursivePaths
+	}
+
+	secrets := make(map[string]string)
+	origins := make(map[string]string)
+	for _, spec := range paths {
+		data, _, err := getSecrets(client, spec, filesDir, "")
+		if err != nil {
+			return nil, err
+		}
+		path, _ := splitPathSpec(spec)
+		for key, value := range data {
+			if err := mergeSecret(secrets, origins, key, value, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// rawValue приводит значение секрета к строке без кавычек и экранирования - переменная
+// передается процессу напрямую, а не через shell
+func rawValue(value interface{}) string {
+	if strValue, ok := value.(string); ok {
+		return strValue
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// signalExitCode возвращает код завершения 128+N для сигнала N, как это делает shell
+func signalExitCode(sig os.Signal) int {
+	if number, ok := sig.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+	return exitError
+}
+
+// childExitCode возвращает код завершения дочернего процесса.
+// Если процесс убит сигналом, возвращает 128+N, как это делает shell
+func childExitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		Log(Error, fmt.Sprintf("Ошибка при ожидании завершения команды: %s", err))
+		return exitError
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return signalExitCode(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,62 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	tests := []struct {
+		name       string
+		secrets    string // файл секретов вместо Vault
+		wantCode   int
+		wantStdout string
+	}{
+		{
+			name:       "секреты в окружении и файлах",
+			secrets:    "secret/app:\n  TOKEN: t1\n  ca.crt: CERT\n",
+			wantStdout: "t1 CERT",
+		},
+		{
+			name:     "ошибка записи файла",
+			secrets:  "secret/app:\n  TOKEN: t1\n  missing/ca.crt: CERT\n",
+			wantCode: exitError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tmp := filepath.Join(dir, "tmp")
+			writeTestFile(t, filepath.Join(dir, "secrets.yaml"), tt.secrets)
+			writeTestFile(t, filepath.Join(tmp, ".keep"), "")
+			env := map[string]string{
+				"HYDRA_SECRETS_FILE": "secrets.yaml",
+				"VAULT_SECRET_PATH":  "secret/app",
+				"TMPDIR":             tmp,
+			}
+			code, stdout, stderr := runHydra(t, dir, env, "exec", "--", "sh", "-c", `printf '%s %s' "$TOKEN" "$(cat "$HYDRA_FILES_DIR/ca.crt")"`)
+			if code != tt.wantCode {
+				t.Fatalf("код завершения %d, ожидался %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			if tt.wantStdout != "" && stdout != tt.wantStdout {
+				t.Errorf("вывод команды %q, ожидался %q", stdout, tt.wantStdout)
+			}
+			// Временная директория с файловыми секретами удаляется и при ошибке
+			entries, err := os.ReadDir(tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("во временной директории остались файлы: %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// outputFormat описывает формат файла, который создает inject
+type outputFormat struct {
+	Name        string
+	Extension   string // Добавляется к имени файла (envs, envs.json и т.д.)
+	Description string
+	Render      func(keys []string, envVars map[string]string) (string, error)
+}
+
+// Формат по умолчанию - KEY="value" с кавычками по ОС и base64 для многострочных значений
+const defaultOutputFormat = "hydra"
+
+var outputFormats = []outputFormat{
+	{Name: "hydra", Description: "KEY=\"value\", многострочные значения в base64 (формат по умолчанию)", Render: renderHydra},
+	{Name: "dotenv", Extension: ".env", Description: "строгий dotenv: KEY='value', многострочные значения сохраняются", Render: renderDotenv},
+	{Name: "export", Extension: ".sh", Description: "export KEY='value' для source в POSIX shell", Render: renderExport},
+	{Name: "json", Extension: ".json", Description: "JSON объект {\"KEY\": \"value\"}", Render: renderJSON},
+	{Name: "yaml", Extension: ".yaml", Description: "YAML словарь KEY: value", Render: renderYAML},
+	{Name: "powershell", Extension: ".ps1", Description: "$env:KEY = 'value' для PowerShell", Render: renderPowerShell},
+	{Name: "bat", Extension: ".bat", Description: "set \"KEY=value\" для cmd.exe, многострочные значения не поддерживаются", Render: renderBat},
+	{Name: "gitlab-dotenv", Extension: ".gitlab.env", Description: "отчет artifacts:reports:dotenv для GitLab: KEY=value, без кавычек и многострочных значений, с лимитами GitLab", Render: renderGitlabDotenv},
+	{Name: "docker", Extension: ".docker.env", Description: "docker run --env-file: KEY=value без кавычек, многострочные значения не поддерживаются", Render: renderDocker},
+}
+
+// findOutputFormat ищет формат по имени
+func findOutputFormat(name string) (outputFormat, bool) {
+	for _, format := range outputFormats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return outputFormat{}, false
+}
+
+// outputFormatNames возвращает имена всех форматов через запятую
+func outputFormatNames() string {
+	names := make([]string, 0, len(outputFormats))
+	for _, format := range outputFormats {
+		names = append(names, format.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// renderEnvs форматирует переменные в выбранном формате. Ключи сортируются, чтобы файл не менялся между запусками
+func renderEnvs(format outputFormat, envVars map[string]string) (string, error) {
+	keys := make([]string, 0, len(envVars))
+	for key := range envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return format.Render(keys, envVars)
+}
+
+func renderHydra(keys []string, envVars map[string]string) (string, error) {
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, processValue(envVars[key]))
+	}
+	return b.String(), nil
+}
+
+// renderDotenv пишет значения в одинарных кавычках - их содержимое dotenv парсеры не интерпретируют.
+// Если в значении есть одинарная кавычка, используются двойные кавычки с экранированием \, " и $ -
+// docker compose, python-dotenv и GitLab раскрывают $VAR и ${...} внутри двойных кавычек
+func renderDotenv(keys []string, envVars map[string]string) (string, error) {
+	var b strings.Builder
+	for _, key := range keys {
+		value := envVars[key]
+		if !strings.Contains(value, "'") {
+			fmt.Fprintf(&b, "%s='%s'\n", key, value)
+			continue
+		}
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(value)
+		fmt.Fprintf(&b, "%s=\"%s\"\n", key, value)
+	}
+	return b.String(), nil
+}
+
+// renderExport пишет export KEY='value'. Внутри одинарных кавычек POSIX shell ничего не раскрывает,
+// одинарная кавычка закрывает строку, добавляется экранированной и строка открывается снова
+func renderExport(keys []string, envVars map[string]string) (string, error) {
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "export %s='%s'\n", key, strings.ReplaceAll(envVars[key], "'", `'\''`))
+	}
+	return b.String(), nil
+}
+
+func renderJSON(keys []string, envVars map[string]string) (string, error) {
+	data, err := json.MarshalIndent(envVars, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// renderYAML пишет словарь в порядке ключей, многострочные значения попадают в блок |.
+// Ключи и значения помечаются как строки, чтобы NULL, TRUE или 0123 не читались как null, bool или число
+func renderYAML(keys []string, envVars map[string]string) (string, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: envVars[key]})
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// renderPowerShell пишет $env:KEY = 'value'. В строках в одинарных кавычках PowerShell
+// ничего не раскрывает, кавычка удваивается, перевод строки допустим
+func renderPowerShell(keys []string, envVars map[string]string) (string, error) {
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "$env:%s = '%s'\n", key, strings.ReplaceAll(envVars[key], "'", "''"))
+	}
+	return b.String(), nil
+}
+
+// renderBat пишет set "KEY=value". В bat файле % нужно удваивать, остальные спецсимволы cmd
+// внутри кавычек безопасны. Перевод строки в переменную cmd записать нельзя
+func renderBat(keys []string, envVars map[string]string) (string, error) {
+	if err := checkSingleLine("bat", keys, envVars); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("@echo off\r\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "set \"%s=%s\"\r\n", key, strings.ReplaceAll(envVars[key], "%", "%%"))
+	}
+	return b.String(), nil
+}
+
+// renderDocker пишет KEY=value как есть: docker --env-file не поддерживает кавычки и экранирование
+func renderDocker(keys []string, envVars map[string]string) (string, error) {
+	if err := checkSingleLine("docker", keys, envVars); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, envVars[key])
+	}
+	return b.String(), nil
+}
+
+// Лимиты dotenv отчета GitLab по умолчанию (dotenv_variables и dotenv_size в настройках инстанса)
+const (
+	defaultDotenvMaxVariables = 20
+	defaultDotenvMaxSize      = 5 * 1024
+)
+
+// renderGitlabDotenv пишет отчет для artifacts:reports:dotenv. GitLab читает значение как есть:
+// кавычки не снимаются, переводы строк, пустые строки и комментарии не поддерживаются.
+// Если хотя бы одно значение нельзя записать или превышены лимиты, возвращается ошибка со списком всех проблем
+func renderGitlabDotenv(keys []string, envVars map[string]string) (string, error) {
+	var problems []string
+	var b strings.Builder
+	for _, key := range keys {
+		value := envVars[key]
+		if valid, _ := isValidKey(key); !valid {
+			problems = append(problems, fmt.Sprintf("%s: недопустимое имя переменной", key))
+			continue
+		}
+		switch {
+		case strings.ContainsAny(value, "\r\n"):
+			problems = append(problems, fmt.Sprintf("%s: многострочное значение", key))
+		case !utf8.ValidString(value):
+			problems = append(problems, fmt.Sprintf("%s: значение не в UTF-8", key))
+		case len(value) >= 2 && strings.ContainsAny(value[:1], `"'`) && value[len(value)-1] == value[0]:
+			problems = append(problems, fmt.Sprintf("%s: значение в кавычках, GitLab сохранит кавычки как часть значения", key))
+		default:
+			fmt.Fprintf(&b, "%s=%s\n", key, value)
+		}
+	}
+
+	maxVariables := dotenvLimit("HYDRA_DOTENV_MAX_VARIABLES", defaultDotenvMaxVariables)
+	if len(keys) > maxVariables {
+		problems = append(problems, fmt.Sprintf("переменных %d, GitLab принимает не больше %d (HYDRA_DOTENV_MAX_VARIABLES)", len(keys), maxVariables))
+	}
+	maxSize := dotenvLimit("HYDRA_DOTENV_MAX_SIZE", defaultDotenvMaxSize)
+	if b.Len() > maxSize {
+		problems = append(problems, fmt.Sprintf("размер отчета %d байт, GitLab принимает не больше %d (HYDRA_DOTENV_MAX_SIZE)", b.Len(), maxSize))
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("секреты нельзя записать в dotenv отчет GitLab:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	Log(Info, "Значения из dotenv отчета доступны всем, у кого есть доступ к артефактам задания - не используйте его для секретов")
+	return b.String(), nil
+}
+
+// dotenvLimit читает лимит dotenv отчета из настройки, при некорректном значении возвращает лимит GitLab по умолчанию
+func dotenvLimit(name string, fallback int) int {
+	limit, err := strconv.Atoi(setting(name))
+	if err != nil || limit <= 0 {
+		return fallback
+	}
+	return limit
+}
+
+// checkSingleLine возвращает ошибку со списком ключей, значения которых нельзя записать в формат без переводов строки
+func checkSingleLine(format string, keys []string, envVars map[string]string) error {
+	var multiline []string
+	for _, key := range keys {
+		if strings.ContainsAny(envVars[key], "\r\n") {
+			multiline = append(multiline, key)
+		}
+	}
+	if len(multiline) > 0 {
+		return fmt.Errorf("формат %s не поддерживает многострочные значения, ключи: %s", format, strings.Join(multiline, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func TestRenderYAML(t *testing.T) {
+	envVars := map[string]string{
+		"NULL":  "null",
+		"TRUE":  "yes",
+		"PORT":  "0123",
+		"MULTI": "a\nb",
+	}
+	format, _ := findOutputFormat("yaml")
+	out, err := renderEnvs(format, envVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("yaml не читается: %v\n%s", err, out)
+	}
+	want := map[string]interface{}{}
+	for key, value := range envVars {
+		want[key] = value
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("прочитано %#v, ожидается %#v\n%s", got, want, out)
+	}
+}
+
+func TestRenderDotenv(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "простое значение", value: "p@ss$word", want: "KEY='p@ss$word'\n"},
+		{name: "многострочное значение", value: "a\nb", want: "KEY='a\nb'\n"},
+		{name: "одинарная кавычка", value: `it's "ok"`, want: `KEY="it's \"ok\""` + "\n"},
+		{name: "кавычка и подстановка", value: `it's $HOME ${USER} \n`, want: `KEY="it's \$HOME \${USER} \\n"` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderDotenv([]string{"KEY"}, map[string]string{"KEY": tt.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("получено %q, ожидается %q", got, tt.want)
+			}
+		})
+	}
+}
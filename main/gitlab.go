@@ -37,7 +37,7 @@ func setGitLabVars(projectID, token string, vars map[string]string) error {
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("PRIVATE-TOKEN", token)
 
-		client := &http.Client{}
+		client := currentRetryPolicy().HTTPClient(nil, hydraLog)
 		resp, err := client.Do(req)
 		if err != nil {
 			return err
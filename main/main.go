@@ -10,6 +10,7 @@ import (
 	"github.com/fatih/color"
 	vault "github.com/hashicorp/vault/api"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -17,58 +18,180 @@ const GoVersion = "1.23.4"
 const version = "4.0.3"
 
 var (
-	showHelp       bool
-	token          string
-	authPath       string
-	authToken      string
-	secretpath     string
-	vaultNamespace string
-	operation      string
-	verbosity      int
-	verbosityint   int
-	red            = color.New(color.FgRed).PrintfFunc()
-	green          = color.New(color.FgGreen).PrintfFunc()
-	cyan           = color.New(color.FgCyan).PrintfFunc()
-	Error          = 1
-	Info           = 2
-	Debug          = 3
-
-	envsPath          string
-	client            *vault.Client
-	vaultAddr         = strings.TrimRight(os.Getenv("VAULT_ADDR"), "\r")
-	vaultAuthRole     = os.Getenv("VAULT_AUTH_ROLE")
-	vaultSecretPaths  = os.Getenv("VAULT_SECRET_PATH")
-	vaultIDToken      = os.Getenv("VAULT_ID_TOKEN")
-	vaultk8sAuthEnv   = os.Getenv("VAULT_K8S_AUTH")
-	vaultk8sToken     = vaultk8s(checkVaultK8sAuthEnv())
-	fileFolderPath    = os.Getenv("VAULT_FILES_PATH")
-	ciProjectDir      = os.Getenv("CI_PROJECT_DIR") // Для Gitlab
-	certsPath         = os.Getenv("VAULT_CA_PATH")
-	vaultAuthUrl      = os.Getenv("VAULT_AUTH_URL")
-	vaultToken        = os.Getenv("VAULT_TOKEN")
-	gitlabGroupID     = os.Getenv("CI_PROJECT_NAMESPACE_ID")
-	vaultWritePath    = os.Getenv("VAULT_WRITE_PATH")
-	gitlabApiUrl      = os.Getenv("CI_API_V4_URL")
-	gitlabProjectID   = os.Getenv("CI_PROJECT_ID")
-	gitlabApiToken    = os.Getenv("GITLAB_API_TOKEN")
-	backupPath        = os.Getenv("VAULT_BACKUP_PATH")
-	osType            = getOS()
-	insecure          = os.Getenv("VAULT_INSECURE")
-	vaultRecursiveEnv = os.Getenv("VAULT_RECURSIVE")
-	VaultExcludeRegex = os.Getenv("VAULT_EXCLUDE_REGEX")
-	SecVaultAddr      = os.Getenv("SEC_VAULT_ADDR")
-	SecVaultToken     = os.Getenv("SEC_VAULT_TOKEN")
-	SecVaultAuthRole  = os.Getenv("SEC_VAULT_AUTH_ROLE")
-	SecVaultAuthUrl   = os.Getenv("SEC_VAULT_AUTH_URL")
+	token        string
+	authPath     string
+	authToken    string
+	secretpath   string
+	operation    string
+	verbosity    int
+	verbosityint int
+	red          = color.New(color.FgRed).FprintfFunc()
+	green        = color.New(color.FgGreen).FprintfFunc()
+	cyan         = color.New(color.FgCyan).FprintfFunc()
+	Error        = 1
+	Info         = 2
+	Debug        = 3
+
+	envsPath           string
+	client             *vault.Client
+	vaultAddr          string
+	vaultAuthRole      string
+	vaultSecretPaths   string
+	vaultIDToken       string
+	vaultk8sAuthEnv    string
+	vaultk8sToken      string
+	fileFolderPath     string
+	ciProjectDir       string
+	certsPath          string
+	clientCertPath     string
+	clientKeyPath      string
+	vaultAuthUrl       string
+	vaultToken         string
+	gitlabGroupID      string
+	vaultWritePath     string
+	gitlabApiUrl       string
+	gitlabProjectID    string
+	gitlabApiToken     string
+	backupPath         string
+	osType             = getOS()
+	insecure           string
+	vaultRecursiveEnv  string
+	VaultExcludeRegex  string
+	outputFormatName   string
+	keyCollisionPolicy string
+	SecVaultAddr       string
+	SecVaultToken      string
+	SecVaultAuthRole   string
+	SecVaultAuthUrl    string
+	vaultNamespace     string
+	SecVaultNamespace  string
+	secretsFile        string
+	SecSecretsFile     string
+
+	/// APPROLE ///
+
+	vaultRoleID             string
+	vaultSecretID           string
+	vaultSecretIDFile       string
+	vaultSecretIDWrapped    bool
+	vaultAppRolePath        string
+	SecVaultRoleID          string
+	SecVaultSecretID        string
+	SecVaultSecretIDFile    string
+	SecVaultSecretIDWrapped bool
+	SecVaultAppRolePath     string
+
+	/// TLS CERT AUTH ///
+
+	vaultCertAuth        bool
+	vaultCertRole        string
+	vaultCertAuthPath    string
+	SecVaultCertAuth     bool
+	SecVaultCertRole     string
+	SecVaultCertAuthPath string
+
+	/// USERPASS / LDAP ///
+
+	vaultUsername        string
+	vaultPassword        string
+	vaultPasswordFile    string
+	vaultLoginMethod     string
+	vaultLoginPath       string
+	SecVaultUsername     string
+	SecVaultPassword     string
+	SecVaultPasswordFile string
+	SecVaultLoginMethod  string
+	SecVaultLoginPath    string
 
 	/// OPENSHIFT-SYNC ///
 
-	okdUsername  = strings.TrimRight(os.Getenv("OC_USERNAME"), "\r")
-	okdPassword  = strings.TrimRight(os.Getenv("OC_PASSWORD"), "\r")
-	ocNameSpaces = splitStringToList(os.Getenv("OC_NAMESPACES"), ",")
-	ocCluster    = strings.TrimRight(os.Getenv("OC_CLUSTER"), "\r")
-	oauthURL     = strings.TrimRight("https://oauth-openshift.apps."+ocCluster+"."+domain, "\r")
-	apiURL       = strings.TrimRight("https://api."+ocCluster+"."+domain+":6443", "\r")
+	okdUsername  string
+	okdPassword  string
+	ocNameSpaces []string
+	ocCluster    string
+	oauthURL     string
+	apiURL       string
+
+	/// Auth Configs ///
+	primaryConfig   AuthConfig
+	secondaryConfig AuthConfig
+)
+
+// loadSettings заполняет глобальные переменные из флагов, переменных окружения и файла конфигурации.
+// Вызывается после разбора командной строки, чтобы флаги имели наивысший приоритет
+func loadSettings() {
+	vaultAddr = strings.TrimRight(setting("VAULT_ADDR"), "\r")
+	vaultAuthRole = setting("VAULT_AUTH_ROLE")
+	vaultSecretPaths = setting("VAULT_SECRET_PATH")
+	vaultIDToken = setting("VAULT_ID_TOKEN")
+	vaultk8sAuthEnv = setting("VAULT_K8S_AUTH")
+	vaultk8sToken = vaultk8s(checkVaultK8sAuthEnv())
+	fileFolderPath = setting("VAULT_FILES_PATH")
+	ciProjectDir = setting("CI_PROJECT_DIR") // Для Gitlab
+	certsPath = setting("VAULT_CA_PATH")
+	clientCertPath = setting("VAULT_CLIENT_CERT")
+	clientKeyPath = setting("VAULT_CLIENT_KEY")
+	vaultAuthUrl = setting("VAULT_AUTH_URL")
+	vaultToken = setting("VAULT_TOKEN")
+	gitlabGroupID = setting("CI_PROJECT_NAMESPACE_ID")
+	vaultWritePath = setting("VAULT_WRITE_PATH")
+	gitlabApiUrl = setting("CI_API_V4_URL")
+	gitlabProjectID = setting("CI_PROJECT_ID")
+	gitlabApiToken = setting("GITLAB_API_TOKEN")
+	backupPath = setting("VAULT_BACKUP_PATH")
+	insecure = setting("VAULT_INSECURE")
+	vaultRecursiveEnv = setting("VAULT_RECURSIVE")
+	VaultExcludeRegex = setting("VAULT_EXCLUDE_REGEX")
+	outputFormatName = setting("HYDRA_OUTPUT_FORMAT")
+	keyCollisionPolicy = setting("HYDRA_KEY_COLLISION")
+	SecVaultAddr = setting("SEC_VAULT_ADDR")
+	SecVaultToken = setting("SEC_VAULT_TOKEN")
+	SecVaultAuthRole = setting("SEC_VAULT_AUTH_ROLE")
+	SecVaultAuthUrl = setting("SEC_VAULT_AUTH_URL")
+	vaultNamespace = strings.Trim(setting("VAULT_NAMESPACE"), "/")
+	SecVaultNamespace = strings.Trim(setting("SEC_VAULT_NAMESPACE"), "/")
+	secretsFile = setting("HYDRA_SECRETS_FILE")
+	SecSecretsFile = setting("SEC_HYDRA_SECRETS_FILE")
+
+	/// APPROLE ///
+	vaultRoleID = setting("VAULT_ROLE_ID")
+	vaultSecretID = setting("VAULT_SECRET_ID")
+	vaultSecretIDFile = setting("VAULT_SECRET_ID_FILE")
+	vaultSecretIDWrapped = checkBoolEnv("VAULT_SECRET_ID_WRAPPED")
+	vaultAppRolePath = setting("VAULT_APPROLE_PATH")
+	SecVaultRoleID = setting("SEC_VAULT_ROLE_ID")
+	SecVaultSecretID = setting("SEC_VAULT_SECRET_ID")
+	SecVaultSecretIDFile = setting("SEC_VAULT_SECRET_ID_FILE")
+	SecVaultSecretIDWrapped = checkBoolEnv("SEC_VAULT_SECRET_ID_WRAPPED")
+	SecVaultAppRolePath = setting("SEC_VAULT_APPROLE_PATH")
+
+	/// TLS CERT AUTH ///
+	vaultCertAuth = checkBoolEnv("VAULT_CERT_AUTH")
+	vaultCertRole = setting("VAULT_CERT_ROLE")
+	vaultCertAuthPath = setting("VAULT_CERT_AUTH_PATH")
+	SecVaultCertAuth = checkBoolEnv("SEC_VAULT_CERT_AUTH")
+	SecVaultCertRole = setting("SEC_VAULT_CERT_ROLE")
+	SecVaultCertAuthPath = setting("SEC_VAULT_CERT_AUTH_PATH")
+
+	/// USERPASS / LDAP ///
+	vaultUsername = setting("VAULT_USERNAME")
+	vaultPassword = setting("VAULT_PASSWORD")
+	vaultPasswordFile = setting("VAULT_PASSWORD_FILE")
+	vaultLoginMethod = setting("VAULT_LOGIN_METHOD")
+	vaultLoginPath = setting("VAULT_LOGIN_PATH")
+	SecVaultUsername = setting("SEC_VAULT_USERNAME")
+	SecVaultPassword = setting("SEC_VAULT_PASSWORD")
+	SecVaultPasswordFile = setting("SEC_VAULT_PASSWORD_FILE")
+	SecVaultLoginMethod = setting("SEC_VAULT_LOGIN_METHOD")
+	SecVaultLoginPath = setting("SEC_VAULT_LOGIN_PATH")
+
+	/// OPENSHIFT-SYNC ///
+	okdUsername = strings.TrimRight(setting("OC_USERNAME"), "\r")
+	okdPassword = strings.TrimRight(setting("OC_PASSWORD"), "\r")
+	ocNameSpaces = splitStringToList(setting("OC_NAMESPACES"), ",")
+	ocCluster = strings.TrimRight(setting("OC_CLUSTER"), "\r")
+	oauthURL = strings.TrimRight("https://oauth-openshift.apps."+ocCluster+"."+domain, "\r")
+	apiURL = strings.TrimRight("https://api."+ocCluster+"."+domain+":6443", "\r")
 
 	/// Auth Configs ///
 	primaryConfig = AuthConfig{
@@ -78,6 +201,23 @@ var (
 		IDToken:    vaultIDToken,
 		AuthUrl:    vaultAuthUrl,
 		VaultRole:  vaultAuthRole,
+		Namespace:  vaultNamespace,
+
+		RoleID:          vaultRoleID,
+		SecretID:        vaultSecretID,
+		SecretIDFile:    vaultSecretIDFile,
+		SecretIDWrapped: vaultSecretIDWrapped,
+		AppRolePath:     vaultAppRolePath,
+
+		CertAuth:     vaultCertAuth,
+		CertRole:     vaultCertRole,
+		CertAuthPath: vaultCertAuthPath,
+
+		Username:     vaultUsername,
+		Password:     vaultPassword,
+		PasswordFile: vaultPasswordFile,
+		LoginMethod:  vaultLoginMethod,
+		LoginPath:    vaultLoginPath,
 	}
 	secondaryConfig = AuthConfig{
 		VaultAddr:  SecVaultAddr,
@@ -86,57 +226,209 @@ var (
 		IDToken:    vaultIDToken,
 		AuthUrl:    SecVaultAuthUrl,
 		VaultRole:  SecVaultAuthRole,
+		Namespace:  SecVaultNamespace,
+
+		RoleID:          SecVaultRoleID,
+		SecretID:        SecVaultSecretID,
+		SecretIDFile:    SecVaultSecretIDFile,
+		SecretIDWrapped: SecVaultSecretIDWrapped,
+		AppRolePath:     SecVaultAppRolePath,
+
+		CertAuth:     SecVaultCertAuth,
+		CertRole:     SecVaultCertRole,
+		CertAuthPath: SecVaultCertAuthPath,
+
+		Username:     SecVaultUsername,
+		Password:     SecVaultPassword,
+		PasswordFile: SecVaultPasswordFile,
+		LoginMethod:  SecVaultLoginMethod,
+		LoginPath:    SecVaultLoginPath,
 	}
-)
+}
 
 func main() {
+	// Баннер и логи пишутся в stderr, stdout остается для вывода команд
+	cmd, args := parseCommandLine()
+	loadSettings()
 	if osType == "" {
 		HandleError(fmt.Errorf("не удалось определить операционную систему"), "Ошибка при определении ОС", Error)
 	} else {
 		Log(Debug, "Операционная система: %s", osType)
 	}
-	HelloMessage()
-	switch os.Args[1] {
+	if cmd.Name != "help" && cmd.Name != "completion" {
+		HelloMessage()
+	}
+	checkConfig()
+	if err := validateConcurrency(); err != nil {
+		usageError(cmd, err.Error())
+	}
+	if _, err := loadRetryPolicy(); err != nil {
+		usageError(cmd, err.Error())
+	}
+	// exec пересылает сигналы дочернему процессу, agent завершается по сигналу сам
+	if cmd.Name != "exec" && cmd.Name != "agent" {
+		handleSignals()
+	}
+	switch cmd.Name {
 	case "help":
-		{
-			printUsage()
+		printHelp(args)
+	case "completion":
+		printCompletion(cmd, args)
+	case "config":
+		if len(args) != 1 || args[0] != "show" {
+			usageError(cmd, "Ожидается 'hydra config show'")
 		}
+		showConfig()
 	case "init", "unseal":
 		if SecVaultAddr == "" || vaultWritePath == "" {
-			Log(Debug, fmt.Sprintf("Не заданы необходимые переменные, SEC_VAULT_ADDR: %s, VAULT_WRITE_PATH: %s", SecVaultAddr, vaultWritePath))
-			printUsage()
-			os.Exit(2)
-		} else {
-			manageVault(os.Args[1], SecVaultAddr, vaultWritePath)
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, SEC_VAULT_ADDR: %s, VAULT_WRITE_PATH: %s", SecVaultAddr, vaultWritePath))
 		}
-
+		manageVault(cmd.Name, SecVaultAddr, vaultWritePath)
 	case "inject":
-		if vaultAddr == "" || vaultSecretPaths == "" {
-			Log(Debug, fmt.Sprintf("Не заданы необходимые переменные, VAULT_ADDR: %s, VAULT_SECRET_PATHS: %s", vaultAddr, vaultSecretPaths))
-			printUsage()
-			os.Exit(2)
-		} else {
-			inject()
+		if (vaultAddr == "" && secretsFile == "") || vaultSecretPaths == "" {
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, VAULT_ADDR или HYDRA_SECRETS_FILE: %s, VAULT_SECRET_PATH: %s", vaultAddr+secretsFile, vaultSecretPaths))
+		}
+		validateSecretSettings(cmd)
+		if _, ok := findOutputFormat(outputFormatName); !ok {
+			usageError(cmd, fmt.Sprintf("Неизвестный формат '%s', доступны: %s", outputFormatName, outputFormatNames()))
+		}
+		inject()
+	case "exec":
+		if (vaultAddr == "" && secretsFile == "") || vaultSecretPaths == "" {
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, VAULT_ADDR или HYDRA_SECRETS_FILE: %s, VAULT_SECRET_PATH: %s", vaultAddr+secretsFile, vaultSecretPaths))
+		}
+		if len(args) == 0 {
+			usageError(cmd, "Не указана команда для запуска: hydra exec -- <команда> [аргументы]")
+		}
+		validateSecretSettings(cmd)
+		exit(execCommand(args))
+	case "template":
+		if vaultAddr == "" && secretsFile == "" {
+			usageError(cmd, "Не задан VAULT_ADDR или HYDRA_SECRETS_FILE")
+		}
+		specs, err := templateSpecs(args)
+		if err != nil {
+			usageError(cmd, err.Error())
+		}
+		if len(specs) == 0 {
+			usageError(cmd, "Не указаны шаблоны: hydra template source:destination[:mode] или HYDRA_TEMPLATES")
+		}
+		if err := renderTemplates(secretStore(primaryConfig, secretsFile), specs); err != nil {
+			HandleError(err, "Ошибка при рендеринге шаблонов", Error)
+		}
+	case "agent":
+		if vaultAddr == "" {
+			usageError(cmd, "Не задан VAULT_ADDR")
+		}
+		specs, err := templateSpecs(args)
+		if err != nil {
+			usageError(cmd, err.Error())
+		}
+		if vaultSecretPaths == "" && len(specs) == 0 {
+			usageError(cmd, "Нечего отслеживать: задайте VAULT_SECRET_PATH и/или шаблоны")
+		}
+		validateSecretSettings(cmd)
+		if _, ok := findOutputFormat(outputFormatName); !ok {
+			usageError(cmd, fmt.Sprintf("Неизвестный формат '%s', доступны: %s", outputFormatName, outputFormatNames()))
+		}
+		if setting("HYDRA_AGENT_PID") != "" || setting("HYDRA_AGENT_PID_FILE") != "" {
+			if _, err := parseSignal(setting("HYDRA_AGENT_SIGNAL")); err != nil {
+				usageError(cmd, err.Error())
+			}
+		}
+		exit(runAgent(specs))
+	case "versions":
+		if vaultAddr == "" {
+			usageError(cmd, "Не задан VAULT_ADDR")
+		}
+		if len(args) != 1 {
+			usageError(cmd, "Ожидается 'hydra versions <путь>'")
+		}
+		if err := showVersions(os.Stdout, args[0]); err != nil {
+			HandleError(err, "Ошибка при чтении версий", Error)
+		}
+	case "rollback":
+		if vaultAddr == "" {
+			usageError(cmd, "Не задан VAULT_ADDR")
+		}
+		if len(args) != 2 {
+			usageError(cmd, "Ожидается 'hydra rollback <путь> <версия>'")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version <= 0 {
+			usageError(cmd, fmt.Sprintf("Некорректная версия: %s", args[1]))
+		}
+		if err := rollbackSecret(os.Stdout, args[0], version); err != nil {
+			HandleError(err, "Ошибка при откате секрета", Error)
 		}
 	case "backup":
-		if backupPath == "" || SecVaultAddr == "" {
-			Log(Debug, fmt.Sprintf("Не заданы необходимые переменные, VAULT_BACKUP_PATH: %s, SEC_VAULT_ADDR: %s", backupPath, SecVaultAddr))
-			printUsage()
-			os.Exit(2)
-		} else {
-			backupSecrets(backupPath)
-
+		if backupPath == "" || (SecVaultAddr == "" && SecSecretsFile == "") {
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, VAULT_BACKUP_PATH: %s, SEC_VAULT_ADDR или SEC_HYDRA_SECRETS_FILE: %s", backupPath, SecVaultAddr+SecSecretsFile))
+		}
+		if mode := setting("HYDRA_BACKUP_MODE"); mode != backupModeFull && mode != backupModeIncremental {
+			usageError(cmd, fmt.Sprintf("Неизвестный режим HYDRA_BACKUP_MODE: %s, допустимые значения: %s, %s", mode, backupModeFull, backupModeIncremental))
+		}
+		if setting("HYDRA_BACKUP_MODE") == backupModeFull && (checkBoolEnv("HYDRA_BACKUP_PRUNE") || checkBoolEnv("HYDRA_BACKUP_STAGING")) {
+			usageError(cmd, "HYDRA_BACKUP_PRUNE и HYDRA_BACKUP_STAGING работают только с HYDRA_BACKUP_MODE=incremental")
+		}
+		backupSecrets(backupPath)
+	case "verify":
+		if (vaultAddr == "" && secretsFile == "") || (SecVaultAddr == "" && SecSecretsFile == "") || backupPath == "" {
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, VAULT_ADDR или HYDRA_SECRETS_FILE: %s, SEC_VAULT_ADDR или SEC_HYDRA_SECRETS_FILE: %s, VAULT_BACKUP_PATH: %s",
+				vaultAddr+secretsFile, SecVaultAddr+SecSecretsFile, backupPath))
+		}
+		if format := setting("HYDRA_VERIFY_FORMAT"); format != verifyFormatText && format != verifyFormatJSON {
+			usageError(cmd, fmt.Sprintf("Неизвестный формат HYDRA_VERIFY_FORMAT: %s, допустимые значения: %s, %s", format, verifyFormatText, verifyFormatJSON))
+		}
+		drift, err := verifySecrets(os.Stdout)
+		if err != nil {
+			HandleError(err, "Ошибка при сверке секретов", Error)
+		}
+		if drift {
+			exit(exitDrift)
+		}
+	case "export":
+		if vaultAddr == "" || backupPath == "" {
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, VAULT_ADDR: %s, VAULT_BACKUP_PATH: %s", vaultAddr, backupPath))
+		}
+		if len(args) > 1 {
+			usageError(cmd, "Ожидается 'hydra export [файл]'")
+		}
+		file := setting("HYDRA_ARCHIVE_FILE")
+		if len(args) == 1 {
+			file = args[0]
+		}
+		if file == "" {
+			file = defaultArchiveFile()
+		}
+		if err := exportArchive(os.Stdout, file); err != nil {
+			HandleError(err, "Ошибка при экспорте секретов", Error)
+		}
+	case "restore":
+		if len(args) > 1 {
+			usageError(cmd, "Ожидается 'hydra restore [архив]'")
+		}
+		file := setting("HYDRA_ARCHIVE_FILE")
+		if len(args) == 1 {
+			file = args[0]
+		}
+		if vaultAddr == "" || (file == "" && (SecVaultAddr == "" || backupPath == "")) {
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, VAULT_ADDR: %s и архив или SEC_VAULT_ADDR: %s с VAULT_BACKUP_PATH: %s", vaultAddr, SecVaultAddr, backupPath))
+		}
+		switch policy := setting("HYDRA_RESTORE_CONFLICT"); policy {
+		case conflictSkip, conflictOverwrite, conflictNewVersion:
+		default:
+			usageError(cmd, fmt.Sprintf("Некорректное значение HYDRA_RESTORE_CONFLICT: %s, ожидается skip, overwrite или new-version", policy))
+		}
+		if err := restoreSecrets(os.Stdout, file); err != nil {
+			HandleError(err, "Ошибка при восстановлении секретов", Error)
 		}
 	case "okd-sync":
 		if okdUsername == "" || okdPassword == "" || ocNameSpaces == nil || ocCluster == "" {
-			Log(Debug, fmt.Sprintf("Не заданы необходимые переменные, проверьте переменные окружения OC_USERNAME, OC_PASSWORD, OC_NAMESPACES: %s, OC_CLUSTER: %s", ocNameSpaces, ocCluster))
-			printUsage()
-			os.Exit(2)
-		} else {
-			okdSync()
-		}
-	default:
-		fmt.Println("Ошибка: неизвестный аргумент, ожидается 'help', 'init', 'unseal', 'inject', 'okd-sync', 'backup'")
-		os.Exit(2)
+			usageError(cmd, fmt.Sprintf("Не заданы необходимые настройки, проверьте OC_USERNAME, OC_PASSWORD, OC_NAMESPACES: %s, OC_CLUSTER: %s", ocNameSpaces, ocCluster))
+		}
+		okdSync()
 	}
+	// Отзываем токены, созданные за время работы команды
+	exit(exitOK)
 }
@@ -0,0 +1,72 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain запускает hydra вместо тестов, если тестовый бинарник вызван из runHydra.
+// Команды завершают процесс через exit, поэтому они проверяются в отдельном процессе
+func TestMain(m *testing.M) {
+	if os.Getenv("HYDRA_TEST_MAIN") == "1" {
+		main()
+		exit(exitOK)
+	}
+	os.Exit(m.Run())
+}
+
+// runHydra запускает hydra с аргументами args в директории dir. Окружение состоит только из env
+// и общих настроек тестов, чтобы на результат не влияли переменные VAULT_* и файл конфигурации разработчика.
+// Возвращает код завершения, stdout и stderr
+func runHydra(t *testing.T, dir string, env map[string]string, args ...string) (int, string, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"HYDRA_TEST_MAIN=1",
+		"HOME=" + dir,
+		"PATH=" + os.Getenv("PATH"),
+		"VAULT_INSECURE=true",
+		"VAULT_VERBOSE=2",
+		"HYDRA_RETRY_ATTEMPTS=1",
+	}
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), stdout.String(), stderr.String()
+	case err != nil:
+		t.Fatalf("не удалось запустить hydra: %v", err)
+	}
+	return exitOK, stdout.String(), stderr.String()
+}
+
+// readFile возвращает содержимое файла или пустую строку, если файла нет
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -15,19 +15,18 @@ import (
 	"errors"
 	"fmt"
 	vault "github.com/hashicorp/vault/api"
+	"hydra/pkg/hydra"
 	"io"
 	"net/http"
-	"os"
 	"regexp"
 	"strings"
-	"time"
 )
 
 func okdSync() {
 	client, err := auth(primaryConfig)
 	if err != nil {
 		HandleError(err, "Ошибка при аутентификации", Error)
-		os.Exit(1)
+		exit(1)
 	}
 	okdClient, err := createHTTPClient(certsPath)
 	if err != nil {
@@ -85,7 +84,7 @@ func getOkdToken(okdClient *http.Client, username, password, url string) (string
 	sha256Token := extractTokens(string(body))
 	if sha256Token == "" {
 		Log(Error, "токен не найден")
-		os.Exit(1)
+		exit(1)
 	}
 
 	return sha256Token, csrfToken, nil
@@ -111,7 +110,7 @@ func createHTTPClient(certsPath string) (*http.Client, error) {
 		TLSClientConfig: tlsConfig,
 	}
 
-	return &http.Client{Transport: tr, Timeout: 5 * time.Second}, nil
+	return currentRetryPolicy().HTTPClient(tr, hydraLog), nil
 }
 
 // FinalOkdToken для запроса извлечения конечного API токена
@@ -158,7 +157,7 @@ func FinalOkdToken(okdClient *http.Client, username, password, openshiftURL, sha
 		return match[1], nil
 	} else {
 		Log(Error, "Токен не найден")
-		os.Exit(1)
+		exit(1)
 	}
 	return "", nil
 }
@@ -258,6 +257,7 @@ func splitStringToList(input string, separator string) []string {
 }
 
 func WriteTokensToVault(okdClient *http.Client, client *vault.Client, token, openshiftURL, ocCluster string, namespaces []string) error {
+	store := libClient(client)
 	for _, namespace := range namespaces {
 		serviceAccounts, err := getServiceAccounts(okdClient, token, openshiftURL+"/api/v1/namespaces/"+namespace+"/serviceaccounts")
 		if err != nil {
@@ -281,7 +281,7 @@ func WriteTokensToVault(okdClient *http.Client, client *vault.Client, token, ope
 
 			// Записываем токен в Vault
 			vaultPath := fmt.Sprintf("%s/%s/%s/%s", vaultWritePath, ocCluster, namespace, sa)
-			err = writeTokenToVault(client, vaultPath, tokenValue, sa, namespace)
+			err = writeTokenToVault(store, vaultPath, tokenValue, sa, namespace)
 			if err != nil {
 				Log(Error, fmt.Sprintf("ошибка при записи токена в Vault для сервисного аккаунта %s в неймспейсе %s: %s\n", sa, namespace, err))
 			}
@@ -369,7 +369,7 @@ func getTokenFromSecret(okdClient *http.Client, token, openshiftURL, namespace,
 }
 
 // Функция для записи токена в Vault
-func writeTokenToVault(client *vault.Client, vaultPath, tokenValue, secretName, namespace string) error {
+func writeTokenToVault(client hydra.SecretStore, vaultPath, tokenValue, secretName, namespace string) error {
 	Log(Info, fmt.Sprintf("%s %s ", vaultPath, "Done"))
 	data := map[string]interface{}{
 		"OPENSHIFT_TOKEN":  tokenValue,
@@ -0,0 +1,54 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"hydra/pkg/hydra"
+	"strconv"
+)
+
+// concurrency возвращает число параллельных запросов к Vault из HYDRA_CONCURRENCY
+func concurrency() int {
+	workers, err := strconv.Atoi(setting("HYDRA_CONCURRENCY"))
+	if err != nil || workers < 1 {
+		return 1
+	}
+	return workers
+}
+
+// rateLimit возвращает ограничение запросов в секунду к одному Vault из HYDRA_RATE_LIMIT, 0 - без ограничения
+func rateLimit() float64 {
+	limit, err := strconv.ParseFloat(setting("HYDRA_RATE_LIMIT"), 64)
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// validateConcurrency проверяет HYDRA_CONCURRENCY и HYDRA_RATE_LIMIT
+func validateConcurrency() error {
+	if workers, err := strconv.Atoi(setting("HYDRA_CONCURRENCY")); err != nil || workers < 1 {
+		return fmt.Errorf("некорректное значение HYDRA_CONCURRENCY: %s, ожидается целое число от 1", setting("HYDRA_CONCURRENCY"))
+	}
+	if value := setting("HYDRA_RATE_LIMIT"); value != "" {
+		if limit, err := strconv.ParseFloat(value, 64); err != nil || limit < 0 {
+			return fmt.Errorf("некорректное значение HYDRA_RATE_LIMIT: %s, ожидается число запросов в секунду, 0 - без ограничения", value)
+		}
+	}
+	return nil
+}
+
+// runParallel выполняет fn для каждого элемента items не более чем в concurrency() горутинах.
+// Результаты возвращаются в порядке items, после первой ошибки новые элементы не запускаются
+func runParallel[T, R any](items []T, fn func(T) (R, error)) ([]R, error) {
+	return hydra.RunParallel(context.Background(), concurrency(), items, func(_ context.Context, item T) (R, error) {
+		return fn(item)
+	})
+}
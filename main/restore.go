@@ -0,0 +1,199 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"hydra/pkg/hydra"
+	"io"
+	"strings"
+)
+
+// Политики восстановления секрета, который уже есть в целевом Vault
+const (
+	conflictSkip       = "skip"        // секрет не трогаем
+	conflictOverwrite  = "overwrite"   // для KV v2 история секрета удаляется, остается только восстановленная версия
+	conflictNewVersion = "new-version" // восстановленные данные записываются новой версией поверх истории
+)
+
+// Действия над секретом при восстановлении, выводятся в план
+const (
+	restoreCreate    = "create"
+	restoreUpdate    = "update"
+	restoreSkip      = "skip"
+	restoreUnchanged = "unchanged"
+)
+
+// restoreResult - итог восстановления по действиям
+type restoreResult map[string]int
+
+// restoreSecrets выполняет команду restore: читает секреты из архива (если он указан)
+// или из вторичного Vault по VAULT_BACKUP_PATH и записывает их в основной Vault
+func restoreSecrets(w io.Writer, archiveFile string) error {
+	policy := setting("HYDRA_RESTORE_CONFLICT")
+	dryRun := checkBoolEnv("HYDRA_RESTORE_DRY_RUN")
+	mapping, err := parsePathMapping(setting("HYDRA_RESTORE_PREFIX"))
+	if err != nil {
+		return err
+	}
+
+	var secrets []archiveSecret
+	if archiveFile != "" {
+		if _, secrets, err = readArchive(archiveFile); err != nil {
+			return err
+		}
+	} else {
+		Log(Info, fmt.Sprintf("Восстановление %s из %s", backupPath, describeVault(secondaryConfig.VaultAddr, secondaryConfig.Namespace)))
+		source, err := auth(secondaryConfig)
+		if err != nil {
+			exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации во вторичном Vault: %w", err))
+		}
+		roots, err := backupRoots(source, backupPath)
+		if err != nil {
+			return err
+		}
+		if secrets, err = collectArchiveSecrets(libClient(source), roots); err != nil {
+			return err
+		}
+	}
+
+	target, err := auth(primaryConfig)
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+	}
+	if dryRun {
+		fmt.Fprintf(w, "План восстановления в %s (dry-run, изменения не записываются):\n", describeVault(primaryConfig.VaultAddr, primaryConfig.Namespace))
+	}
+
+	var included []archiveSecret
+	for _, secret := range secrets {
+		if excludeString(secret.Path) == nil {
+			Log(Info, fmt.Sprintf("путь '%s' был исключен на основе регулярного выражения", secret.Path))
+			continue
+		}
+		included = append(included, secret)
+	}
+	// Секреты записываются параллельно, план и итог выводятся в порядке архива
+	store := libClient(target)
+	actions, err := runParallel(included, func(secret archiveSecret) (string, error) {
+		return restoreSecret(store, mapping.apply(secret.Path), secret, policy, dryRun)
+	})
+	if err != nil {
+		return err
+	}
+
+	result := restoreResult{}
+	for i, secret := range included {
+		path, action := mapping.apply(secret.Path), actions[i]
+		result[action]++
+		if path != secret.Path {
+			fmt.Fprintf(w, "  %-9s %s (из %s)\n", action, path, secret.Path)
+		} else {
+			fmt.Fprintf(w, "  %-9s %s\n", action, path)
+		}
+	}
+	fmt.Fprintf(w, "Создано: %d, изменено: %d, пропущено: %d, без изменений: %d\n",
+		result[restoreCreate], result[restoreUpdate], result[restoreSkip], result[restoreUnchanged])
+	return nil
+}
+
+// restoreSecret сравнивает секрет с целевым Vault и записывает его по политике конфликтов.
+// Вместе с данными в KV v2 записываются настройки из метаданных архива.
+// Возвращает действие, которое выполнено (или было бы выполнено в dry-run)
+func restoreSecret(client *hydra.Client, path string, secret archiveSecret, policy string, dryRun bool) (string, error) {
+	data := secret.Data
+	existingJSON, _ := readSecret(client, path)
+	action := restoreCreate
+	if existingJSON != nil {
+		existing, err := unmarshalSecret(existingJSON, path)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case hydra.SameData(existing, data):
+			return restoreUnchanged, nil
+		case policy == conflictSkip:
+			return restoreSkip, nil
+		}
+		action = restoreUpdate
+	}
+	if dryRun {
+		return action, nil
+	}
+
+	if action == restoreUpdate && policy == conflictOverwrite {
+		// Для KV v2 удаляем все версии, для KV v1 запись и так заменяет секрет
+		if _, err := readMetadata(client, path); err == nil {
+			if err := client.Delete(context.Background(), path); err != nil {
+				return "", fmt.Errorf("не удалось удалить историю секрета %s: %v", path, err)
+			}
+			Log(Debug, fmt.Sprintf("История секрета %s удалена перед восстановлением", path))
+		}
+	}
+	if _, err := writeSecret(client, path, data); err != nil {
+		return "", fmt.Errorf("ошибка при записи секрета %s: %v", path, err)
+	}
+	// Метаданные пишутся после данных: в KV v1 их нет, и запись в metadata/ создала бы лишний секрет
+	if secret.Metadata != nil {
+		if _, err := readMetadata(client, path); err == nil {
+			if err := client.WriteMetadata(context.Background(), path, secret.Metadata); err != nil {
+				return "", err
+			}
+		}
+	}
+	return action, nil
+}
+
+// pathMapping - правила переноса путей при восстановлении
+type pathMapping struct {
+	prefix string            // добавляется ко всем путям, если не задано ни одного правила from=to
+	rules  map[string]string // старый префикс -> новый префикс
+}
+
+// parsePathMapping разбирает HYDRA_RESTORE_PREFIX: пары старый=новый через пробел
+// (myns=restored/myns) или один префикс, который добавляется ко всем путям (restored)
+func parsePathMapping(value string) (pathMapping, error) {
+	mapping := pathMapping{rules: map[string]string{}}
+	for _, field := range strings.Fields(value) {
+		from, to, found := strings.Cut(field, "=")
+		if !found {
+			if mapping.prefix != "" {
+				return mapping, fmt.Errorf("в HYDRA_RESTORE_PREFIX можно указать только один общий префикс: %s", value)
+			}
+			mapping.prefix = strings.Trim(field, "/")
+			continue
+		}
+		from, to = strings.Trim(from, "/"), strings.Trim(to, "/")
+		if from == "" || to == "" {
+			return mapping, fmt.Errorf("некорректное правило '%s' в HYDRA_RESTORE_PREFIX, ожидается старый=новый", field)
+		}
+		mapping.rules[from] = to
+	}
+	if mapping.prefix != "" && len(mapping.rules) > 0 {
+		return mapping, fmt.Errorf("в HYDRA_RESTORE_PREFIX нельзя смешивать общий префикс и правила старый=новый: %s", value)
+	}
+	return mapping, nil
+}
+
+// apply возвращает новый путь секрета. Из правил выбирается самый длинный совпавший префикс
+func (m pathMapping) apply(path string) string {
+	if m.prefix != "" {
+		return m.prefix + "/" + path
+	}
+	best := ""
+	for from := range m.rules {
+		if (path == from || strings.HasPrefix(path, from+"/")) && len(from) > len(best) {
+			best = from
+		}
+	}
+	if best == "" {
+		return path
+	}
+	return m.rules[best] + strings.TrimPrefix(path, best)
+}
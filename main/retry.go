@@ -0,0 +1,58 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"fmt"
+	"hydra/pkg/hydra"
+	"strconv"
+	"time"
+)
+
+// loadRetryPolicy читает общую политику повторов запросов к Vault, GitLab и OpenShift из HYDRA_RETRY_ATTEMPTS, HYDRA_RETRY_WAIT,
+// HYDRA_RETRY_MAX_WAIT и HYDRA_REQUEST_TIMEOUT
+func loadRetryPolicy() (hydra.RetryPolicy, error) {
+	var policy hydra.RetryPolicy
+	attempts, err := strconv.Atoi(setting("HYDRA_RETRY_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		return policy, fmt.Errorf("некорректное значение HYDRA_RETRY_ATTEMPTS: %s, ожидается целое число от 1", setting("HYDRA_RETRY_ATTEMPTS"))
+	}
+	policy.Attempts = attempts
+	if policy.WaitMin, err = durationSetting("HYDRA_RETRY_WAIT"); err != nil {
+		return policy, err
+	}
+	if policy.WaitMax, err = durationSetting("HYDRA_RETRY_MAX_WAIT"); err != nil {
+		return policy, err
+	}
+	if policy.WaitMax < policy.WaitMin {
+		return policy, fmt.Errorf("HYDRA_RETRY_MAX_WAIT (%s) меньше HYDRA_RETRY_WAIT (%s)", policy.WaitMax, policy.WaitMin)
+	}
+	if policy.Timeout, err = durationSetting("HYDRA_REQUEST_TIMEOUT"); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// currentRetryPolicy возвращает политику повторов. Настройки проверены в main через loadRetryPolicy
+func currentRetryPolicy() hydra.RetryPolicy {
+	policy, _ := loadRetryPolicy()
+	return policy
+}
+
+// durationSetting читает настройку-длительность: длительность Go (500ms, 30s) или число секунд
+func durationSetting(name string) (time.Duration, error) {
+	value := setting(name)
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("некорректное значение %s: %s, ожидается например 500ms или 30s", name, setting(name))
+	}
+	return duration, nil
+}
@@ -0,0 +1,142 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"fmt"
+	"hydra/pkg/hydra"
+	"sort"
+	"strings"
+)
+
+// keyRule - правила для ключей одного секрета: префикс, переименование и выбор ключей.
+// Задаются в разделе keys файла конфигурации, префикс также можно указать в пути: myns/app/db:DB_
+type keyRule struct {
+	Prefix  string            `yaml:"prefix"`
+	Rename  map[string]string `yaml:"rename"`
+	Include []string          `yaml:"include"`
+	Exclude []string          `yaml:"exclude"`
+}
+
+// Политики при совпадении имен переменных из разных секретов
+const (
+	collisionWarn  = "warn"
+	collisionError = "error"
+)
+
+// Правила из раздела keys файла конфигурации, ключ - путь секрета
+var configKeyRules map[string]keyRule
+
+// splitPathSpec разделяет путь из VAULT_SECRET_PATH на путь секрета и префикс ключей: myns/app/db:DB_
+func splitPathSpec(spec string) (string, string) {
+	path, prefix, _ := strings.Cut(spec, ":")
+	return path, prefix
+}
+
+// joinPathSpec добавляет префикс к пути, найденному при рекурсивном обходе
+func joinPathSpec(path, prefix string) string {
+	if prefix == "" {
+		return path
+	}
+	return path + ":" + prefix
+}
+
+// normalizeSecretPath приводит путь к виду из конфигурации: без /data/ KV v2 и крайних /
+func normalizeSecretPath(path string) string {
+	return hydra.NormalizePath(path)
+}
+
+// keyRuleFor возвращает правила для секрета. Префикс из пути перекрывает префикс из конфигурации
+func keyRuleFor(path, prefix string) keyRule {
+	rule := configKeyRules[normalizeSecretPath(path)]
+	if prefix != "" {
+		rule.Prefix = prefix
+	}
+	return rule
+}
+
+// selected проверяет ключ по спискам include и exclude
+func (r keyRule) selected(key string) bool {
+	if len(r.Include) > 0 && !containsKey(r.Include, key) {
+		return false
+	}
+	return !containsKey(r.Exclude, key)
+}
+
+// envName возвращает имя переменной для ключа в верхнем регистре: явное имя из rename или префикс + ключ.
+// Ключ в rename ищется без учета регистра, как в include и exclude
+func (r keyRule) envName(key string) string {
+	for from, name := range r.Rename {
+		if strings.EqualFold(from, key) {
+			return processKey(name)
+		}
+	}
+	return processKey(r.Prefix + key)
+}
+
+// validate проверяет rename: имя переменной должно проходить isValidKey, а ключ - встречаться
+// один раз без учета регистра, иначе выбор имени зависел бы от порядка обхода карты
+func (r keyRule) validate() error {
+	seen := make(map[string]string, len(r.Rename))
+	for from, name := range r.Rename {
+		if previous, ok := seen[processKey(from)]; ok {
+			return fmt.Errorf("ключ %s в rename указан дважды: %s и %s", processKey(from), previous, from)
+		}
+		seen[processKey(from)] = from
+		if valid, _ := isValidKey(processKey(name)); !valid || name == "" {
+			return fmt.Errorf("некорректное имя переменной в rename %s: '%s'", from, name)
+		}
+	}
+	return nil
+}
+
+// containsKey ищет ключ в списке без учета регистра, так как имена переменных все равно приводятся к верхнему
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSecretSettings проверяет настройки чтения секретов для inject, exec и agent:
+// HYDRA_KEY_COLLISION, версии в VAULT_SECRET_PATH и rename в разделе keys. При ошибке выводит справку по команде и завершает работу
+func validateSecretSettings(cmd *cliCommand) {
+	if keyCollisionPolicy != collisionWarn && keyCollisionPolicy != collisionError {
+		usageError(cmd, fmt.Sprintf("Некорректное значение HYDRA_KEY_COLLISION: %s, ожидается warn или error", keyCollisionPolicy))
+	}
+	if err := validateSecretPaths(vaultSecretPaths); err != nil {
+		usageError(cmd, err.Error())
+	}
+	paths := make([]string, 0, len(configKeyRules))
+	for path := range configKeyRules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := configKeyRules[path].validate(); err != nil {
+			usageError(cmd, fmt.Sprintf("keys: %s: %s", path, err))
+		}
+	}
+}
+
+// mergeSecret добавляет переменную и проверяет, что она не перезаписывает переменную из другого секрета.
+// origins хранит путь, из которого взята каждая переменная
+func mergeSecret(secrets, origins map[string]string, name, value, path string) error {
+	if previous, exists := origins[name]; exists && previous != path {
+		message := fmt.Sprintf("переменная %s из %s перезаписывает переменную из %s, используйте префикс или rename", name, path, previous)
+		if keyCollisionPolicy == collisionError {
+			return fmt.Errorf("%s", message)
+		}
+		Log(Error, "Предупреждение: "+message)
+	}
+	secrets[name] = value
+	origins[name] = path
+	return nil
+}
@@ -0,0 +1,49 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import "testing"
+
+func TestKeyRuleEnvName(t *testing.T) {
+	rule := keyRule{Prefix: "DB_", Rename: map[string]string{"user": "pguser", "Pass": "PGPASSWORD"}}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "user", want: "PGUSER"},
+		{key: "USER", want: "PGUSER"},
+		{key: "pass", want: "PGPASSWORD"},
+		{key: "host", want: "DB_HOST"},
+	}
+	for _, tt := range tests {
+		if got := rule.envName(tt.key); got != tt.want {
+			t.Errorf("envName(%q) = %q, ожидается %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeyRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rename  map[string]string
+		wantErr bool
+	}{
+		{name: "корректные имена", rename: map[string]string{"user": "pguser", "password": "PG_PASSWORD"}},
+		{name: "недопустимые символы", rename: map[string]string{"user": "PG-USER"}, wantErr: true},
+		{name: "пустое имя", rename: map[string]string{"user": ""}, wantErr: true},
+		{name: "ключ в разном регистре", rename: map[string]string{"user": "A", "USER": "B"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := keyRule{Rename: tt.rename}.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate: %v, ожидается ошибка: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
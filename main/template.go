@@ -0,0 +1,362 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"hydra/pkg/hydra"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Права на файлы шаблонов по умолчанию - в них обычно секреты
+const defaultTemplateMode = "0600"
+
+// templateSpec описывает один шаблон: исходный файл, файл результата и права на него.
+// В командной строке задается как source:destination[:mode], в файле конфигурации - разделом templates
+type templateSpec struct {
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination"`
+	Mode        string `yaml:"mode"`
+}
+
+// Шаблоны из раздела templates файла конфигурации
+var configTemplates []templateSpec
+
+// secretCache хранит секреты, прочитанные за один запуск, чтобы все файлы
+// были отрендерены из одного и того же состояния Vault
+type secretCache struct {
+	client  hydra.SecretStore
+	secrets map[string]map[string]interface{}
+	wanted  map[string]bool
+}
+
+// parseTemplateSpec разбирает source:destination[:mode]. На Windows пути могут начинаться с буквы диска:
+// C:\tpl\app.tmpl:C:\out\app.yaml:0600 - двоеточие после нее разделителем не считается
+func parseTemplateSpec(spec string) (templateSpec, error) {
+	parts := splitTemplateSpec(spec)
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return templateSpec{}, fmt.Errorf("некорректный шаблон '%s', ожидается source:destination[:mode]", spec)
+	}
+	result := templateSpec{Source: parts[0], Destination: parts[1]}
+	if len(parts) == 3 {
+		if !isOctal(parts[2]) {
+			return templateSpec{}, fmt.Errorf("некорректные права '%s' в шаблоне '%s', ожидается восьмеричное число, например 0644", parts[2], spec)
+		}
+		result.Mode = parts[2]
+	}
+	return result, nil
+}
+
+// splitTemplateSpec делит шаблон по двоеточиям, пропуская двоеточие после буквы диска в начале пути на Windows
+func splitTemplateSpec(spec string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != ':' || (osType == "windows" && isDrivePrefix(spec[start:], i-start)) {
+			continue
+		}
+		parts = append(parts, spec[start:i])
+		start = i + 1
+	}
+	return append(parts, spec[start:])
+}
+
+// isDrivePrefix сообщает, что двоеточие на позиции colon в пути path идет после буквы диска: C:\ или C:/
+func isDrivePrefix(path string, colon int) bool {
+	if colon != 1 || len(path) < 3 || (path[2] != '\\' && path[2] != '/') {
+		return false
+	}
+	letter := path[0] | 0x20
+	return letter >= 'a' && letter <= 'z'
+}
+
+// isOctal сообщает, что строка - непустое восьмеричное число
+func isOctal(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, char := range value {
+		if char < '0' || char > '7' {
+			return false
+		}
+	}
+	return true
+}
+
+// fileMode разбирает права на файл в восьмеричном виде, например 0644
+func (s templateSpec) fileMode() (os.FileMode, error) {
+	mode := s.Mode
+	if mode == "" {
+		mode = defaultTemplateMode
+	}
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || value > 0777 {
+		return 0, fmt.Errorf("некорректные права '%s' для %s, ожидается восьмеричное число, например 0644", s.Mode, s.Destination)
+	}
+	return os.FileMode(value), nil
+}
+
+// templateSpecs собирает шаблоны из аргументов команды, HYDRA_TEMPLATES и файла конфигурации
+func templateSpecs(args []string) ([]templateSpec, error) {
+	specs := append([]templateSpec{}, configTemplates...)
+	for _, spec := range append(strings.Fields(setting("HYDRA_TEMPLATES")), args...) {
+		parsed, err := parseTemplateSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, parsed)
+	}
+	for _, spec := range specs {
+		if _, err := spec.fileMode(); err != nil {
+			return nil, err
+		}
+	}
+	return specs, nil
+}
+
+// loadTemplates разбирает шаблоны и собирает из них пути секретов
+func loadTemplates(specs []templateSpec) ([]*template.Template, *secretCache, error) {
+	templates := make([]*template.Template, len(specs))
+	cache := &secretCache{secrets: map[string]map[string]interface{}{}, wanted: map[string]bool{}}
+	for i, spec := range specs {
+		content, err := os.ReadFile(spec.Source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("не удалось прочитать шаблон %s: %v", spec.Source, err)
+		}
+		templates[i], err = template.New(filepath.Base(spec.Source)).Funcs(cache.funcs()).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка в шаблоне %s: %v", spec.Source, err)
+		}
+		for _, tmpl := range templates[i].Templates() {
+			if tmpl.Tree != nil {
+				cache.collectPaths(tmpl.Tree.Root)
+			}
+		}
+	}
+	return templates, cache, nil
+}
+
+// renderTemplates рендерит все шаблоны.
+// Сначала из всех шаблонов собираются пути секретов, затем они читаются разом,
+// и только после этого файлы рендерятся и записываются
+func renderTemplates(client hydra.SecretStore, specs []templateSpec) error {
+	templates, cache, err := loadTemplates(specs)
+	if err != nil {
+		return err
+	}
+	cache.client = client
+	if err := cache.fetchWanted(); err != nil {
+		return err
+	}
+
+	rendered := make([][]byte, len(specs))
+	for i, spec := range specs {
+		var out bytes.Buffer
+		if err := templates[i].Execute(&out, nil); err != nil {
+			return fmt.Errorf("ошибка при рендеринге шаблона %s: %v", spec.Source, err)
+		}
+		rendered[i] = out.Bytes()
+	}
+
+	// Файлы пишутся только когда все шаблоны отрендерены, чтобы не оставить часть файлов обновленной
+	for i, spec := range specs {
+		mode, _ := spec.fileMode()
+		if err := writeFileAtomic(spec.Destination, rendered[i], mode); err != nil {
+			return err
+		}
+		Log(Info, fmt.Sprintf("Шаблон %s записан в %s (%s)", spec.Source, spec.Destination, mode))
+	}
+	return nil
+}
+
+// fetchWanted читает все пути, найденные в шаблонах, до начала рендеринга
+func (c *secretCache) fetchWanted() error {
+	paths := make([]string, 0, len(c.wanted))
+	for path := range c.wanted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, err := c.read(path); err != nil {
+			return err
+		}
+	}
+	Log(Info, fmt.Sprintf("Прочитано секретов для шаблонов: %d", len(paths)))
+	return nil
+}
+
+// collectPaths ищет в дереве шаблона вызовы secret и secretValue с путем-строкой
+func (c *secretCache) collectPaths(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.collectPaths(child)
+		}
+	case *parse.ActionNode:
+		c.collectPaths(n.Pipe)
+	case *parse.IfNode:
+		c.collectBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		c.collectBranch(&n.BranchNode)
+	case *parse.WithNode:
+		c.collectBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		c.collectPaths(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			c.collectPaths(cmd)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 1 {
+			ident, isIdent := n.Args[0].(*parse.IdentifierNode)
+			path, isString := n.Args[1].(*parse.StringNode)
+			if isIdent && isString && (ident.Ident == "secret" || ident.Ident == "secretValue") {
+				c.wanted[path.Text] = true
+			}
+		}
+		for _, arg := range n.Args {
+			c.collectPaths(arg)
+		}
+	case *parse.ChainNode:
+		c.collectPaths(n.Node)
+	}
+}
+
+func (c *secretCache) collectBranch(n *parse.BranchNode) {
+	c.collectPaths(n.Pipe)
+	c.collectPaths(n.List)
+	c.collectPaths(n.ElseList)
+}
+
+// read возвращает секрет из кэша или читает его из Vault
+func (c *secretCache) read(path string) (map[string]interface{}, error) {
+	if data, ok := c.secrets[path]; ok {
+		return data, nil
+	}
+	if c.wanted != nil && !c.wanted[path] {
+		// Путь вычисляется при рендеринге (например из другого секрета) и не был прочитан заранее
+		Log(Debug, fmt.Sprintf("Секрет %s читается во время рендеринга", path))
+	}
+	secretDataJSON, err := readSecretSpec(c.client, path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении секрета %s: %v", path, err)
+	}
+	if secretDataJSON == nil {
+		return nil, fmt.Errorf("секрет %s не найден", path)
+	}
+	data, err := unmarshalSecret(secretDataJSON, path)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	c.secrets[path] = data
+	return data, nil
+}
+
+// funcs возвращает функции, доступные в шаблонах
+func (c *secretCache) funcs() template.FuncMap {
+	return template.FuncMap{
+		// secret "myns/app/db" - все ключи секрета: {{ (secret "myns/app/db").password }}
+		"secret": c.read,
+		// secretValue "myns/app/db" "password" - значение одного ключа
+		"secretValue": func(path, key string) (string, error) {
+			data, err := c.read(path)
+			if err != nil {
+				return "", err
+			}
+			value, ok := data[key]
+			if !ok {
+				return "", fmt.Errorf("ключ %s не найден в секрете %s", key, path)
+			}
+			return rawValue(value), nil
+		},
+		"env": os.Getenv,
+		"envDefault": func(name, fallback string) string {
+			if value := os.Getenv(name); value != "" {
+				return value
+			}
+			return fallback
+		},
+		"base64Encode": func(value string) string {
+			return base64.StdEncoding.EncodeToString([]byte(value))
+		},
+		"base64Decode": func(value string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			return string(decoded), err
+		},
+		"toJSON": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		"fromJSON": func(value string) (interface{}, error) {
+			var result interface{}
+			err := json.Unmarshal([]byte(value), &result)
+			return result, err
+		},
+		"toYAML": func(value interface{}) (string, error) {
+			data, err := yaml.Marshal(value)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"fromYAML": func(value string) (interface{}, error) {
+			var result interface{}
+			err := yaml.Unmarshal([]byte(value), &result)
+			return result, err
+		},
+		// indent 4 .cert - сдвигает все строки, например для многострочного значения в YAML
+		"indent": func(spaces int, value string) string {
+			pad := strings.Repeat(" ", spaces)
+			return pad + strings.ReplaceAll(value, "\n", "\n"+pad)
+		},
+	}
+}
+
+// writeFileAtomic пишет файл через временный файл в той же директории,
+// чтобы приложение никогда не прочитало наполовину записанный файл
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("не удалось создать директорию %s: %v", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("не удалось создать временный файл для %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("не удалось записать %s: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("не удалось записать %s: %v", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("не удалось установить права на %s: %v", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("не удалось записать %s: %v", path, err)
+	}
+	return nil
+}
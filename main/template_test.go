@@ -0,0 +1,44 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import "testing"
+
+func TestParseTemplateSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		os      string
+		spec    string
+		want    templateSpec
+		wantErr bool
+	}{
+		{name: "без прав", os: "linux", spec: "app.tmpl:out/app.yaml", want: templateSpec{Source: "app.tmpl", Destination: "out/app.yaml"}},
+		{name: "с правами", os: "linux", spec: "/tpl/app.tmpl:/out/app.yaml:0644", want: templateSpec{Source: "/tpl/app.tmpl", Destination: "/out/app.yaml", Mode: "0644"}},
+		{name: "нет результата", os: "linux", spec: "app.tmpl", wantErr: true},
+		{name: "права не восьмеричные", os: "linux", spec: "app.tmpl:app.yaml:0800", wantErr: true},
+		{name: "лишняя часть", os: "linux", spec: "a:b:0644:c", wantErr: true},
+		{name: "буква диска только на Windows", os: "linux", spec: `C:\tpl\app.tmpl:C:\out\app.yaml`, wantErr: true},
+		{name: "Windows", os: "windows", spec: `C:\tpl\app.tmpl:C:\out\app.yaml`, want: templateSpec{Source: `C:\tpl\app.tmpl`, Destination: `C:\out\app.yaml`}},
+		{name: "Windows с правами", os: "windows", spec: `c:/tpl/app.tmpl:D:\out\app.yaml:0600`, want: templateSpec{Source: "c:/tpl/app.tmpl", Destination: `D:\out\app.yaml`, Mode: "0600"}},
+		{name: "Windows относительный путь", os: "windows", spec: `app.tmpl:C:\out\app.yaml`, want: templateSpec{Source: "app.tmpl", Destination: `C:\out\app.yaml`}},
+		{name: "Windows без результата", os: "windows", spec: `C:\tpl\app.tmpl`, wantErr: true},
+	}
+	defer func(previous string) { osType = previous }(osType)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			osType = tt.os
+			got, err := parseTemplateSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTemplateSpec(%q): %v, ожидается ошибка: %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTemplateSpec(%q) = %+v, ожидается %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
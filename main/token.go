@@ -0,0 +1,210 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"fmt"
+	vault "github.com/hashicorp/vault/api"
+	"hydra/pkg/hydra"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// managedToken - токен, который Hydra получила сама при авторизации.
+// Такой токен продлевается в фоне, а при завершении команды отзывается
+type managedToken struct {
+	client     *vault.Client
+	authClient *hydra.Client // клиент библиотеки, через который выполняется повторная авторизация
+	authConfig AuthConfig
+	loginResp  *vault.Secret
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+}
+
+var (
+	managedTokens   []*managedToken
+	managedTokensMu sync.Mutex
+	exitOnce        sync.Once
+)
+
+// Через сколько повторить авторизацию, если Vault временно недоступен
+const reauthRetryInterval = 10 * time.Second
+
+// manageToken регистрирует токен, полученный при авторизации, и запускает его продление
+func manageToken(client *hydra.Client, authConfig AuthConfig, loginResp *vault.Secret) {
+	auth := loginResp.Auth
+	Log(Debug, fmt.Sprintf("Получен токен для %s: TTL %s, продлеваемый: %t",
+		describeVault(authConfig.VaultAddr, authConfig.Namespace), time.Duration(auth.LeaseDuration)*time.Second, auth.Renewable))
+
+	token := &managedToken{
+		client:     client.Vault(),
+		authClient: client,
+		authConfig: authConfig,
+		loginResp:  loginResp,
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+	managedTokensMu.Lock()
+	managedTokens = append(managedTokens, token)
+	managedTokensMu.Unlock()
+
+	go token.watch()
+}
+
+// watch продлевает токен, пока это возможно, а когда продление не удалось
+// или достигнут максимальный TTL - авторизуется заново тем же способом
+func (t *managedToken) watch() {
+	defer close(t.doneCh)
+	for {
+		if t.loginResp.Auth.Renewable {
+			if !t.renew() {
+				return
+			}
+		} else if !t.waitExpiry() {
+			return
+		}
+
+		for {
+			loginResp, err := t.relogin()
+			if err == nil {
+				t.loginResp = loginResp
+				break
+			}
+			Log(Error, fmt.Sprintf("Не удалось повторно авторизоваться в %s: %s", describeVault(t.authConfig.VaultAddr, t.authConfig.Namespace), err))
+			select {
+			case <-t.stopCh:
+				return
+			case <-time.After(reauthRetryInterval):
+			}
+		}
+	}
+}
+
+// renew продлевает токен через LifetimeWatcher.
+// Возвращает false, если продление остановлено при завершении команды
+func (t *managedToken) renew() bool {
+	watcher, err := t.client.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: t.loginResp})
+	if err != nil {
+		Log(Error, fmt.Sprintf("Не удалось запустить продление токена: %s", err))
+		return t.waitExpiry()
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-t.stopCh:
+			return false
+		case renewal := <-watcher.RenewCh():
+			Log(Debug, fmt.Sprintf("Токен для %s продлен, TTL %s", t.authConfig.VaultAddr,
+				time.Duration(renewal.Secret.Auth.LeaseDuration)*time.Second))
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				Log(Error, fmt.Sprintf("Ошибка при продлении токена для %s: %s", t.authConfig.VaultAddr, err))
+			} else {
+				Log(Info, fmt.Sprintf("Токен для %s больше не продлевается, авторизуемся заново", t.authConfig.VaultAddr))
+			}
+			return true
+		}
+	}
+}
+
+// waitExpiry ждет истечения непродлеваемого токена с небольшим запасом
+func (t *managedToken) waitExpiry() bool {
+	ttl := time.Duration(t.loginResp.Auth.LeaseDuration) * time.Second
+	if ttl <= 0 {
+		// Токен без срока действия продлевать не нужно
+		<-t.stopCh
+		return false
+	}
+	select {
+	case <-t.stopCh:
+		return false
+	case <-time.After(ttl * 9 / 10):
+		Log(Info, fmt.Sprintf("Срок действия токена для %s истекает, авторизуемся заново", t.authConfig.VaultAddr))
+		return true
+	}
+}
+
+// relogin авторизуется заново тем же способом и подставляет новый токен в основной клиент.
+// Старый токен при этом отзывается, чтобы не оставлять его живым
+func (t *managedToken) relogin() (*vault.Secret, error) {
+	oldToken := t.client.Token()
+	loginResp, err := t.authClient.Login(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if oldClient, err := t.client.Clone(); err == nil {
+		oldClient.SetToken(oldToken)
+		if namespace := t.client.Namespace(); namespace != "" {
+			oldClient.SetNamespace(namespace)
+		}
+		// Старый токен мог уже истечь - ошибку отзыва не считаем критичной
+		if err := oldClient.Auth().Token().RevokeSelf(""); err != nil {
+			Log(Debug, fmt.Sprintf("Не удалось отозвать старый токен: %s", err))
+		}
+	}
+	Log(Info, fmt.Sprintf("Повторная авторизация в %s выполнена", describeVault(t.authConfig.VaultAddr, t.authConfig.Namespace)))
+	return loginResp, nil
+}
+
+// revokeManagedTokens останавливает продление и отзывает все токены, созданные Hydra.
+// Токен из VAULT_TOKEN сюда не попадает и не отзывается
+func revokeManagedTokens() {
+	managedTokensMu.Lock()
+	tokens := managedTokens
+	managedTokens = nil
+	managedTokensMu.Unlock()
+
+	for _, token := range tokens {
+		close(token.stopCh)
+		<-token.doneCh
+		// Batch токены нельзя отозвать, они истекают сами
+		if isBatchToken(token.client.Token()) {
+			continue
+		}
+		if err := token.client.Auth().Token().RevokeSelf(""); err != nil {
+			Log(Error, fmt.Sprintf("Не удалось отозвать токен для %s: %s", token.authConfig.VaultAddr, err))
+			continue
+		}
+		Log(Debug, fmt.Sprintf("Токен для %s отозван", describeVault(token.authConfig.VaultAddr, token.authConfig.Namespace)))
+	}
+}
+
+// isBatchToken определяет batch токен по префиксу (hvb. в новых версиях Vault, b. в старых)
+func isBatchToken(token string) bool {
+	return strings.HasPrefix(token, "hvb.") || strings.HasPrefix(token, "b.")
+}
+
+// exit отзывает созданные токены и завершает процесс с указанным кодом.
+// Используется вместо os.Exit, чтобы токены не оставались живыми после ошибки
+func exit(code int) {
+	exitOnce.Do(revokeManagedTokens)
+	os.Exit(code)
+}
+
+// handleSignals отзывает токены при прерывании команды (SIGINT, SIGTERM)
+func handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		Log(Info, fmt.Sprintf("Получен сигнал %s, завершаем работу", sig))
+		code := 1
+		if s, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(s)
+		}
+		exit(code)
+	}()
+}
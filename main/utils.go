@@ -11,11 +11,11 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	vault "github.com/hashicorp/vault/api"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -33,7 +33,7 @@ func checkVaultK8sAuthEnv() bool {
 	if err != nil {
 		Log(Error, fmt.Sprintf("Некорректное значение VAULT_K8S_AUTH: %s. Ожидалось true/false.", vaultk8sAuthEnv))
 		vaultk8sAuth = false // Устанавливаем значение по умолчанию
-		printUsage()
+		printUsage(os.Stderr)
 	}
 	return vaultk8sAuth
 }
@@ -51,17 +51,33 @@ func checkVaultRecursiveEnv() bool {
 	if err != nil {
 		Log(Error, fmt.Sprintf("Некорректное значение VAULT_RECURSIVE: %s Ожидалось true/false.", vaultRecursiveEnv))
 		vaultRecursive = false // Устанавливаем значение по умолчанию
-		printUsage()
+		printUsage(os.Stderr)
 	}
 	return vaultRecursive
 }
 
+// checkBoolEnv
+// Вернет true если настройка name задана и она true
+// по умолчанию false
+func checkBoolEnv(name string) bool {
+	value := setting(name)
+	if value == "" {
+		return false
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		Log(Error, fmt.Sprintf("Некорректное значение %s: %s. Ожидалось true/false.", name, value))
+		return false
+	}
+	return result
+}
+
 func Log(level int, message string, args ...interface{}) {
 	if setVerbosity() < level {
 		return
 	}
 
-	var logFunction func(format string, a ...interface{})
+	var logFunction func(w io.Writer, format string, a ...interface{})
 	switch level {
 	case Error:
 		logFunction = red
@@ -76,7 +92,7 @@ func Log(level int, message string, args ...interface{}) {
 	if len(args) > 0 {
 		message = fmt.Sprintf(message, args...)
 	}
-	logFunction(fmt.Sprintf("[%s] %s\n", levelToString(level), message))
+	logFunction(os.Stderr, "[%s] %s\n", levelToString(level), message)
 }
 
 func levelToString(level int) string {
@@ -106,12 +122,18 @@ func HandleError(err error, message string, level int) {
 	if err != nil {
 		Log(level, "%s: %v", message, err)
 		if level == Error {
-			os.Exit(1)
+			exit(exitError)
 		}
-		os.Exit(level)
+		exit(level)
 	}
 }
 
+// exitWith выводит ошибку и завершает работу с кодом завершения code, например exitAuth
+func exitWith(code int, err error) {
+	Log(Error, err.Error())
+	exit(code)
+}
+
 func createFile(fileFolderPath, ciProjectDir, key string, value interface{}) error {
 	fileFolderPath = determineFileFolderPath(fileFolderPath, ciProjectDir)
 	if err := ensureDirectory(fileFolderPath); err != nil {
@@ -150,20 +172,13 @@ func joinPaths(basePath, folderPath string) string {
 	return basePath + "/" + folderPath
 }
 
+// writeToFile записывает файловый секрет. Ошибка возвращается вызывающему, а не завершает процесс,
+// чтобы exec успел удалить временную директорию с уже записанными файлами
 func writeToFile(fileName string, data []byte) error {
-	err := os.WriteFile(fileName, data, 0644)
-	if err != nil {
-		HandleError(err, fmt.Sprintf("Ошибка при создании файла '%s'", fileName), Error)
-	}
-	return err
-}
-
-func extractNamespace(path string) string {
-	parts := strings.Split(path, "/")
-	if len(parts) >= 1 {
-		return parts[0]
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return fmt.Errorf("ошибка при создании файла '%s': %w", fileName, err)
 	}
-	return "" // Если нет слешей в строке, возвращаем пустую строку
+	return nil
 }
 
 // Функция всегда возвращает последний элемент массива, что корректно работает для строк без /
@@ -171,34 +186,6 @@ func extractSecretName(path string) string {
 	parts := strings.Split(path, "/")
 	return parts[len(parts)-1]
 }
-func modifyPathForV2(path string, operation string) string {
-	// Удаляем начальный слеш, если он есть
-	trimmedPath := strings.TrimPrefix(path, "/")
-	parts := strings.Split(trimmedPath, "/")
-
-	// Для операций чтения и записи добавляем "data" после монтирования точки.
-	if (operation == "Read" || operation == "Write") && len(parts) > 1 {
-		parts = append([]string{parts[0], "data"}, parts[1:]...)
-	}
-
-	// Для операции List добавляем "metadata" после монтирования точки.
-	if operation == "List" {
-		// Если уже есть "metadata", то не добавляем его снова
-		if len(parts) > 1 && parts[1] != "metadata" {
-			parts = append([]string{parts[0], "metadata"}, parts[1:]...)
-		} else if len(parts) == 1 {
-			// Если в пути только монтирование точки, добавляем "metadata"
-			parts = append(parts, "metadata")
-		}
-	}
-
-	return strings.Join(parts, "/")
-}
-
-// Функция для замены 'metadata' на 'data' в пути для KV V2
-func modifyPathForDisplay(path string) string {
-	return strings.Replace(path, "metadata/", "data/", 1)
-}
 func isFileKey(key string) bool {
 	fileExtensions := []string{".crt", ".jwks", ".pem", ".p12", ".key", ".file", ".txt", ".conf"}
 	for _, ext := range fileExtensions {
@@ -224,10 +211,10 @@ func createEnvsFile(ciProjectDir string, envVars map[string]string, path string)
 		return "", nil
 	}
 	var tmpPath string
-	if os.Getenv("HYDRA_SECRETS_DIR") == "" || len(os.Getenv("HYDRA_SECRETS_DIR")) == 0 {
+	if setting("HYDRA_SECRETS_DIR") == "" {
 		tmpPath = "tmp"
 	} else {
-		tmpPath = os.Getenv("HYDRA_SECRETS_DIR")
+		tmpPath = setting("HYDRA_SECRETS_DIR")
 	}
 	// Проверяем исключения через excludeString
 	excludedPath := excludeString(path)
@@ -236,10 +223,11 @@ func createEnvsFile(ciProjectDir string, envVars map[string]string, path string)
 		return "", nil
 	}
 
-	// Подготовка содержимого для файла
-	envsContent := ""
-	for key, value := range envVars {
-		envsContent += fmt.Sprintf("%s=%s\n", key, value)
+	// Подготовка содержимого для файла в выбранном формате
+	format, _ := findOutputFormat(outputFormatName)
+	envsContent, err := renderEnvs(format, envVars)
+	if err != nil {
+		return "", err
 	}
 	// Убираем "/data" из пути, если он есть и отрезаем "/" в конце
 	sanitizedPath := strings.TrimSuffix(strings.Replace(path, "/data/", "/", -1), "/")
@@ -253,14 +241,10 @@ func createEnvsFile(ciProjectDir string, envVars map[string]string, path string)
 	}
 	if checkVaultRecursiveEnv() {
 		// Если включен рекурсивный режим, создаём структуру директорий
-		envsPath = filepath.Join(dirPath, basePath)
+		envsPath = filepath.Join(dirPath, basePath+format.Extension)
 	} else {
 		// Если рекурсивный режим отключен, создаём единый файл
-		if osType == "linux" || osType == "darwin" {
-			envsPath = filepath.Join(ciProjectDir, tmpPath, "envs")
-		} else {
-			envsPath = filepath.Join(ciProjectDir, tmpPath, "envs")
-		}
+		envsPath = filepath.Join(ciProjectDir, tmpPath, "envs"+format.Extension)
 	}
 	// Создаем директорию, включая все промежуточные каталоги
 	mkdirerr := os.MkdirAll(dirPath, 0755)
@@ -268,7 +252,7 @@ func createEnvsFile(ciProjectDir string, envVars map[string]string, path string)
 		return "", mkdirerr
 	}
 	// Записываем содержимое в файл
-	err := os.WriteFile(envsPath, []byte(envsContent), 0644)
+	err = os.WriteFile(envsPath, []byte(envsContent), 0644)
 	if err != nil {
 		HandleError(err, "Ошибка при записи в файл", 1)
 	}
@@ -298,7 +282,7 @@ func isValidKey(key string) (bool, string) {
 }
 
 func setVerbosity() int {
-	verbosityStr := os.Getenv("VAULT_VERBOSE")
+	verbosityStr := setting("VAULT_VERBOSE")
 	verbosity, err := strconv.Atoi(verbosityStr)
 	if err != nil {
 		verbosity = 1 // Default level is Info
@@ -306,7 +290,7 @@ func setVerbosity() int {
 	return verbosity
 }
 func setVaultInitShares() int {
-	vaultInitSharesStr := os.Getenv("VAULT_INIT_SHARES")
+	vaultInitSharesStr := setting("VAULT_INIT_SHARES")
 	vaultInitShares, err := strconv.Atoi(vaultInitSharesStr)
 	if err != nil {
 		vaultInitShares = 5 // Default Key Init Numbers
@@ -314,7 +298,7 @@ func setVaultInitShares() int {
 	return vaultInitShares
 }
 func setVaultInitTreshold() int {
-	VaultInitTresholdStr := os.Getenv("VAULT_INIT_THRESHOLD")
+	VaultInitTresholdStr := setting("VAULT_INIT_THRESHOLD")
 	VaultInitTreshold, err := strconv.Atoi(VaultInitTresholdStr)
 	if err != nil {
 		VaultInitTreshold = 3 // Default Theshold Keys
@@ -338,53 +322,139 @@ func generateKeyNamesAndMap(vaultInitShares int, initResp *vault.InitResponse) m
 	return keysData
 }
 
-func printUsage() {
-	fmt.Println("Использование: ./hydra [ОПЦИИ]")
-
-	fmt.Println("\nОпции для операций с Vault:")
-	fmt.Println("  - ./hydra init             - (для новых установок) Инициализация и разблокировка вашего $SEC_VAULT_ADDR и запись ключей в $VAULT_ADDR $VAULT_WRITE_PATH")
-	fmt.Println("  - ./hydra unseal           - только разблокировка $SEC_VAULT_ADDR с использованием переменных из $VAULT_ADDR $VAULT_SECRET_PATH")
-	fmt.Println("  - ./hydra inject           - инъекция секретов из $VAULT_ADDR $VAULT_SECRET_PATH в файл окружения")
-	fmt.Println("  - ./hydra okd-sync         - извлечение всех токенов авторизации из учетных записей служб в указанных пространствах имен и запись их в $VAULT_ADDR по пути $VAULT_WRITE_PATH + /$OC_CLUSTER/NAMESPACE/SERVICEACCOUNT")
-	fmt.Println("  - ./hydra backup           - Рекурсивное извлечение всех секретов из пути, указанного в VAULT_BACKUP_PATH, и запись их в SEC_VAULT_ADDR с пересозданием пространства имен и комментарием о дате резервного копирования.")
-	fmt.Println("  - ./hydra help             - вывод этого сообщения о помощи")
-
-	fmt.Println("\nОбязательные переменные окружения:")
-	fmt.Println("  - VAULT_ADDR               : https://vault.***.ru                   # (обязательно) URL master сервера Vault")
-	fmt.Println("  - SEC_VAULT_ADDR           : https://vault.***.ru                   # (обязательно, если вызван init/unseal/backup) URL Second сервера Vault")
-	fmt.Println("  - VAULT_WRITE_PATH         : mysecret/path1                         # **(обязательно, если вызван init/unseal/okd-sync) Путь для записи ключей")
-	fmt.Println("  - VAULT_AUTH_ROLE          : dev                                    # (обязательно, если используется JWT) Роль аутентификации в Primary Vault")
-	fmt.Println("  - SEC_VAULT_AUTH_ROLE      : sec-dev                                # (обязательно, если используется JWT) Роль аутентификации в Second Vault")
-	fmt.Println("  - VAULT_SECRET_PATH        : mysecret/path1 mysecret/path2          # **(обязательно) Пробелами разделенный список путей секретов")
-
-	fmt.Println("\nНеобязательные переменные окружения:")
-	fmt.Println("  - VAULT_TOKEN              : MYPrimaryTOKEN                         # (не обязательно) Токен для авторизации в Primary экземпляр Vault")
-	fmt.Println("  - SEC_VAULT_TOKEN          : MYSecondaryTOKEN                       # (не обязательно) Токен для авторизации в Second экземпляр Vault")
-	fmt.Println("  - VAULT_AUTH_URL           : auth/MYJWTURL/login                    # (не обязательно) URL для входа в Primary Vault")
-	fmt.Println("  - SEC_VAULT_AUTH_URL       : auth/MYJWTURL/login                    # (не обязательно) URL для входа в Second Vault")
-	fmt.Println("  - VAULT_FILES_PATH         : mydir                                  # **(не обязательно) Пользовательский путь для файлов")
-	fmt.Println("  - VAULT_K8S_AUTH           : true/false                             # **(не обязательно)(по умолчанию false) Включает аутентификацию Kubernetes")
-	fmt.Println("  - VAULT_VERBOSE            : 1 (ERROR,INFO,DEBUG - 1,2,3)           # **(по умолчанию 1) Уровень подробности логирования")
-	fmt.Println("  - VAULT_CA_PATH            : cert/mycert.cer                        # **(не обязательно) Путь SSL сертификатов")
-	fmt.Println("  - VAULT_RECURSIVE          : true/false                             # **(не обязательно)(по умолчанию false) Включает рекурсивное чтение секретов")
-	fmt.Println("  - VAULT_REGEX_EXCLUDE      : p-.*?                                  # (не обязательно) Исключение имен секретов с использованием regex")
-
-	fmt.Println("\nДля синхронизации с Openshift/K8S:")
-	fmt.Println("  - OC_USERNAME              : tuz_vapupkin                           # (обязательно для okd-sync) Имя пользователя Openshift/K8S")
-	fmt.Println("  - OC_PASSWORD              : mY$tRonGPa$$W0rD                       # (обязательно для okd-sync) Пароль Openshift/K8S")
-	fmt.Println("  - OC_NAMESPACES            : myns1, myns2, myns3                    # (обязательно для okd-sync) Пространства имен через запятую")
-	fmt.Println("  - OC_CLUSTER               : mycluster                              # (обязательно для okd-sync) Имя кластера")
-
-	fmt.Println("\nДля Init/Unseal Vault:")
-	fmt.Println("  - VAULT_INIT_SHARES        : 5                                      # (не обязательно)(по умолчанию 5) Количество ключей для инициализации")
-	fmt.Println("  - VAULT_INIT_THRESHOLD     : 3                                      # (не обязательно)(по умолчанию 3) Количество ключей для успешной разблокировки")
-	fmt.Println("  - SEC_VAULT_UNSEAL_KEY*    : MYUNSEALKEY1                           # (обязательно для unseal) Ключи для успешной разблокировки")
-
-	fmt.Println("\nДополнительные заметки:")
-	fmt.Println("  - Убедитесь, что обязательные переменные окружения настроены правильно перед запуском операций Vault.")
-	fmt.Println("  - Используйте `VAULT_VERBOSE` для управления уровнем логирования (1 для ERROR, 2 для INFO, 3 для DEBUG).")
-	fmt.Println("  - Переменная SEC_VAULT_UNSEAL_KEY* начинается с SEC_VAULT_UNSEAL_KEY1 и заканчивается на SEC_VAULT_UNSEAL_KEY32.")
-	fmt.Println("  - **Общие переменные используются для обоих экземпляров Vault.")
+// printUsage выводит общую справку в w: в stdout по запросу справки, в stderr после ошибки
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Использование: ./hydra <команда> [флаги] [аргументы]")
+
+	fmt.Fprintln(w, "\nКоманды:")
+	fmt.Fprintln(w, "  - ./hydra init             - (для новых установок) Инициализация и разблокировка вашего $SEC_VAULT_ADDR и запись ключей в $VAULT_ADDR $VAULT_WRITE_PATH")
+	fmt.Fprintln(w, "  - ./hydra unseal           - только разблокировка $SEC_VAULT_ADDR с использованием переменных из $VAULT_ADDR $VAULT_SECRET_PATH")
+	fmt.Fprintln(w, "  - ./hydra inject           - инъекция секретов из $VAULT_ADDR $VAULT_SECRET_PATH в файл окружения")
+	fmt.Fprintln(w, "  - ./hydra exec -- CMD      - запуск CMD с секретами из $VAULT_ADDR $VAULT_SECRET_PATH в переменных окружения без записи файла окружения, код завершения CMD возвращается как есть")
+	fmt.Fprintln(w, "  - ./hydra template SRC:DST - рендеринг файлов из шаблонов text/template с секретами из $VAULT_ADDR, права файла указываются третьим полем (SRC:DST:0640)")
+	fmt.Fprintln(w, "  - ./hydra agent            - постоянная работа: опрос версий секретов и перерисовка файлов inject и template только при изменениях, с командой или сигналом после изменения")
+	fmt.Fprintln(w, "  - ./hydra versions PATH    - история версий секрета KV v2: время создания, удаленные и уничтоженные версии, custom_metadata")
+	fmt.Fprintln(w, "  - ./hydra rollback PATH N  - запись версии N секрета KV v2 как новой последней версии")
+	fmt.Fprintln(w, "  - ./hydra okd-sync         - извлечение всех токенов авторизации из учетных записей служб в указанных пространствах имен и запись их в $VAULT_ADDR по пути $VAULT_WRITE_PATH + /$OC_CLUSTER/NAMESPACE/SERVICEACCOUNT")
+	fmt.Fprintln(w, "  - ./hydra backup           - Рекурсивное извлечение всех секретов из путей, указанных в VAULT_BACKUP_PATH (* - все engine KV), и запись их в SEC_VAULT_ADDR с пересозданием пространства имен и комментарием о дате резервного копирования.")
+	fmt.Fprintln(w, "  - ./hydra verify           - сверка секретов VAULT_BACKUP_PATH в $VAULT_ADDR и $SEC_VAULT_ADDR по хешу: отсутствующие, лишние и отличающиеся пути, код 3 при расхождениях")
+	fmt.Fprintln(w, "  - ./hydra export [FILE]    - экспорт секретов из VAULT_BACKUP_PATH с метаданными KV v2 в архив, зашифрованный age (HYDRA_AGE_RECIPIENTS) или паролем (HYDRA_ARCHIVE_PASSPHRASE)")
+	fmt.Fprintln(w, "  - ./hydra restore [FILE]   - восстановление секретов в $VAULT_ADDR из архива hydra export или из $SEC_VAULT_ADDR по VAULT_BACKUP_PATH, с переносом путей и политикой конфликтов")
+	fmt.Fprintln(w, "  - ./hydra config show      - вывод итоговых настроек (секреты скрыты) с указанием источника каждого значения")
+	fmt.Fprintln(w, "  - ./hydra completion SHELL - вывод скрипта автодополнения для bash, zsh или fish")
+	fmt.Fprintln(w, "  - ./hydra help [команда]   - вывод этого сообщения о помощи или справки по флагам команды (аналог ./hydra <команда> --help)")
+	fmt.Fprintln(w, "  - ./hydra --version        - вывод версии")
+
+	fmt.Fprintln(w, "\nОбязательные переменные окружения:")
+	fmt.Fprintln(w, "  - VAULT_ADDR               : https://vault.***.ru                   # (обязательно) URL master сервера Vault")
+	fmt.Fprintln(w, "  - SEC_VAULT_ADDR           : https://vault.***.ru                   # (обязательно, если вызван init/unseal/backup) URL Second сервера Vault")
+	fmt.Fprintln(w, "  - VAULT_WRITE_PATH         : mysecret/path1                         # **(обязательно, если вызван init/unseal/okd-sync) Путь для записи ключей")
+	fmt.Fprintln(w, "  - VAULT_AUTH_ROLE          : dev                                    # (обязательно, если используется JWT) Роль аутентификации в Primary Vault")
+	fmt.Fprintln(w, "  - SEC_VAULT_AUTH_ROLE      : sec-dev                                # (обязательно, если используется JWT) Роль аутентификации в Second Vault")
+	fmt.Fprintln(w, "  - VAULT_SECRET_PATH        : mysecret/path1 mysecret/path2          # **(обязательно) Пробелами разделенный список путей секретов, путь@N закрепляет версию KV v2, путь:ПРЕФИКС добавляет префикс к ключам")
+
+	fmt.Fprintln(w, "\nНеобязательные переменные окружения:")
+	fmt.Fprintln(w, "  - VAULT_TOKEN              : MYPrimaryTOKEN                         # (не обязательно) Токен для авторизации в Primary экземпляр Vault")
+	fmt.Fprintln(w, "  - SEC_VAULT_TOKEN          : MYSecondaryTOKEN                       # (не обязательно) Токен для авторизации в Second экземпляр Vault")
+	fmt.Fprintln(w, "  - VAULT_AUTH_URL           : auth/MYJWTURL/login                    # (не обязательно) URL для входа в Primary Vault")
+	fmt.Fprintln(w, "  - SEC_VAULT_AUTH_URL       : auth/MYJWTURL/login                    # (не обязательно) URL для входа в Second Vault")
+	fmt.Fprintln(w, "  - VAULT_NAMESPACE          : myteam/dev                             # (не обязательно) Namespace Vault Enterprise для Primary Vault")
+	fmt.Fprintln(w, "  - SEC_VAULT_NAMESPACE      : myteam/backup                          # (не обязательно) Namespace Vault Enterprise для Second Vault")
+	fmt.Fprintln(w, "  - VAULT_FILES_PATH         : mydir                                  # **(не обязательно) Пользовательский путь для файлов")
+	fmt.Fprintln(w, "  - VAULT_K8S_AUTH           : true/false                             # **(не обязательно)(по умолчанию false) Включает аутентификацию Kubernetes")
+	fmt.Fprintln(w, "  - VAULT_VERBOSE            : 1 (ERROR,INFO,DEBUG - 1,2,3)           # **(по умолчанию 1) Уровень подробности логирования")
+	fmt.Fprintln(w, "  - VAULT_CA_PATH            : cert/mycert.cer                        # **(не обязательно) Путь SSL сертификатов")
+	fmt.Fprintln(w, "  - VAULT_RECURSIVE          : true/false                             # **(не обязательно)(по умолчанию false) Включает рекурсивное чтение секретов")
+	fmt.Fprintln(w, "  - HYDRA_KEY_COLLISION      : warn/error                             # (не обязательно)(по умолчанию warn) Совпадение имен переменных из разных секретов: предупреждение или ошибка")
+	fmt.Fprintln(w, "  - HYDRA_TEMPLATES          : app.tmpl:config/app.yaml:0640          # (не обязательно) Шаблоны для hydra template через пробел")
+	fmt.Fprintln(w, "  - HYDRA_ARCHIVE_FILE       : backup.tar.gz.age                      # (не обязательно) Файл архива hydra export")
+	fmt.Fprintln(w, "  - HYDRA_AGE_RECIPIENTS     : age1...                                # (не обязательно) Публичные ключи age для шифрования архива через пробел")
+	fmt.Fprintln(w, "  - HYDRA_AGE_RECIPIENTS_FILE: recipients.txt                         # (не обязательно) Файл с публичными ключами age")
+	fmt.Fprintln(w, "  - HYDRA_ARCHIVE_PASSPHRASE : MyPassphrase                           # (не обязательно) Пароль архива, если не заданы ключи age")
+	fmt.Fprintln(w, "  - HYDRA_ARCHIVE_PASSPHRASE_FILE: /run/secrets/archive               # (не обязательно) Файл с паролем архива")
+	fmt.Fprintln(w, "  - HYDRA_AGE_IDENTITY_FILE  : key.txt                                # (не обязательно) Файл с приватными ключами age для hydra restore")
+	fmt.Fprintln(w, "  - HYDRA_RESTORE_PREFIX     : myns=myns-restore                      # (не обязательно) Новый префикс путей или правила старый=новый для hydra restore")
+	fmt.Fprintln(w, "  - HYDRA_RESTORE_CONFLICT   : skip/overwrite/new-version             # (не обязательно)(по умолчанию skip) Что делать, если секрет уже есть")
+	fmt.Fprintln(w, "  - HYDRA_RESTORE_DRY_RUN    : true/false                             # (не обязательно)(по умолчанию false) Только вывести план восстановления")
+	fmt.Fprintln(w, "  - HYDRA_BACKUP_MODE        : full/incremental                       # (не обязательно)(по умолчанию full) Режим hydra backup: пересоздание engine или только изменения")
+	fmt.Fprintln(w, "  - HYDRA_BACKUP_PRUNE       : true/false                             # (не обязательно)(по умолчанию false) Удалять из копии секреты, которых нет в источнике")
+	fmt.Fprintln(w, "  - HYDRA_BACKUP_STAGING     : true/false                             # (не обязательно)(по умолчанию false) Собирать копию в staging engine и заменять им копию после успеха")
+	fmt.Fprintln(w, "  - HYDRA_BACKUP_HISTORY     : true/false                             # (не обязательно)(по умолчанию false) Копировать все версии, метаданные и настройки engine источника")
+	fmt.Fprintln(w, "  - HYDRA_VERIFY_FORMAT      : text/json                              # (не обязательно)(по умолчанию text) Формат отчета hydra verify в stdout")
+	fmt.Fprintln(w, "  - HYDRA_VERIFY_REPORT      : verify.json                            # (не обязательно) Файл для отчета hydra verify в JSON")
+	fmt.Fprintln(w, "  - HYDRA_SECRETS_FILE       : secrets.yaml                           # (не обязательно) Файл секретов JSON или YAML вместо основного Vault")
+	fmt.Fprintln(w, "  - SEC_HYDRA_SECRETS_FILE   : backup.json                            # (не обязательно) Файл секретов JSON или YAML вместо вторичного Vault для backup и verify")
+	fmt.Fprintln(w, "  - HYDRA_CONCURRENCY        : 4                                      # (не обязательно)(по умолчанию 4) Число параллельных запросов к Vault при обходе, чтении и записи")
+	fmt.Fprintln(w, "  - HYDRA_RATE_LIMIT         : 50                                     # (не обязательно)(по умолчанию без ограничения) Запросов в секунду к одному Vault")
+	fmt.Fprintln(w, "  - HYDRA_RETRY_ATTEMPTS     : 5                                      # (не обязательно)(по умолчанию 5) Число попыток запроса к Vault, GitLab и OpenShift")
+	fmt.Fprintln(w, "  - HYDRA_RETRY_WAIT         : 500ms                                  # (не обязательно)(по умолчанию 500ms) Пауза перед первым повтором, дальше удваивается")
+	fmt.Fprintln(w, "  - HYDRA_RETRY_MAX_WAIT     : 30s                                    # (не обязательно)(по умолчанию 30s) Наибольшая пауза между повторами")
+	fmt.Fprintln(w, "  - HYDRA_REQUEST_TIMEOUT    : 60s                                    # (не обязательно)(по умолчанию 60s) Таймаут одной попытки запроса")
+	fmt.Fprintln(w, "  - HYDRA_AGENT_INTERVAL     : 60s                                    # (не обязательно)(по умолчанию 60s) Интервал опроса Vault в hydra agent")
+	fmt.Fprintln(w, "  - HYDRA_AGENT_COMMAND      : nginx -s reload                        # (не обязательно) Команда hydra agent после изменения секретов")
+	fmt.Fprintln(w, "  - HYDRA_AGENT_SIGNAL       : HUP                                    # (не обязательно)(по умолчанию HUP) Сигнал процессу после изменения секретов")
+	fmt.Fprintln(w, "  - HYDRA_AGENT_PID          : 1234                                   # (не обязательно) PID процесса для сигнала")
+	fmt.Fprintln(w, "  - HYDRA_AGENT_PID_FILE     : /run/nginx.pid                         # (не обязательно) Файл с PID процесса для сигнала")
+	fmt.Fprintln(w, "  - HYDRA_AGENT_HEALTH_ADDR  : :8080                                  # (не обязательно) Адрес health endpoint hydra agent (GET /health)")
+	fmt.Fprintln(w, "  - HYDRA_OUTPUT_FORMAT      : hydra/dotenv/export/json/yaml/powershell/bat/docker/gitlab-dotenv # (не обязательно)(по умолчанию hydra) Формат файла переменных inject")
+	fmt.Fprintln(w, "  - HYDRA_DOTENV_MAX_VARIABLES: 20                                     # (не обязательно)(по умолчанию 20) Лимит переменных dotenv отчета GitLab")
+	fmt.Fprintln(w, "  - HYDRA_DOTENV_MAX_SIZE    : 5120                                   # (не обязательно)(по умолчанию 5120) Лимит размера dotenv отчета GitLab в байтах")
+	fmt.Fprintln(w, "  - VAULT_REGEX_EXCLUDE      : p-.*?                                  # (не обязательно) Исключение имен секретов с использованием regex")
+
+	fmt.Fprintln(w, "  - VAULT_CLIENT_CERT        : cert/client.crt                        # **(не обязательно) Клиентский сертификат для mTLS соединения с Vault")
+	fmt.Fprintln(w, "  - VAULT_CLIENT_KEY         : cert/client.key                        # **(не обязательно) Ключ клиентского сертификата для mTLS")
+
+	fmt.Fprintln(w, "\nДля авторизации через TLS сертификат (для Second Vault используются те же переменные с префиксом SEC_):")
+	fmt.Fprintln(w, "  - VAULT_CERT_AUTH          : true/false                             # (не обязательно)(по умолчанию false) Авторизация по клиентскому сертификату VAULT_CLIENT_CERT")
+	fmt.Fprintln(w, "  - VAULT_CERT_ROLE          : myhost                                 # (не обязательно) Имя роли в cert auth, если не задано - Vault подберет роль сам")
+	fmt.Fprintln(w, "  - VAULT_CERT_AUTH_PATH     : cert                                   # (не обязательно)(по умолчанию cert) Путь монтирования cert auth")
+	fmt.Fprintln(w, "\nДля авторизации через AppRole (для Second Vault используются те же переменные с префиксом SEC_):")
+	fmt.Fprintln(w, "  - VAULT_ROLE_ID            : 0b7c...                                # (обязательно для AppRole) role_id роли AppRole")
+	fmt.Fprintln(w, "  - VAULT_SECRET_ID          : 6a1f...                                # (не обязательно) secret_id роли AppRole")
+	fmt.Fprintln(w, "  - VAULT_SECRET_ID_FILE     : /run/secrets/secret_id                 # (не обязательно) Файл с secret_id, если не задан VAULT_SECRET_ID")
+	fmt.Fprintln(w, "  - VAULT_SECRET_ID_WRAPPED  : true/false                             # (не обязательно)(по умолчанию false) secret_id передан как wrapping токен")
+	fmt.Fprintln(w, "  - VAULT_APPROLE_PATH       : approle                                # (не обязательно)(по умолчанию approle) Путь монтирования AppRole")
+
+	fmt.Fprintln(w, "\nДля авторизации по логину и паролю (для Second Vault используются те же переменные с префиксом SEC_):")
+	fmt.Fprintln(w, "  - VAULT_USERNAME           : vapupkin                               # (обязательно для userpass/ldap) Имя пользователя")
+	fmt.Fprintln(w, "  - VAULT_PASSWORD           : mY$tRonGPa$$W0rD                       # (не обязательно) Пароль, если не задан - читается из VAULT_PASSWORD_FILE или запрашивается в терминале")
+	fmt.Fprintln(w, "  - VAULT_PASSWORD_FILE      : ~/.vault-password                      # (не обязательно) Файл с паролем")
+	fmt.Fprintln(w, "  - VAULT_LOGIN_METHOD       : userpass/ldap                          # (не обязательно)(по умолчанию userpass) Метод авторизации")
+	fmt.Fprintln(w, "  - VAULT_LOGIN_PATH         : ldap-corp                              # (не обязательно)(по умолчанию совпадает с VAULT_LOGIN_METHOD) Путь монтирования метода")
+
+	fmt.Fprintln(w, "\nДля синхронизации с Openshift/K8S:")
+	fmt.Fprintln(w, "  - OC_USERNAME              : tuz_vapupkin                           # (обязательно для okd-sync) Имя пользователя Openshift/K8S")
+	fmt.Fprintln(w, "  - OC_PASSWORD              : mY$tRonGPa$$W0rD                       # (обязательно для okd-sync) Пароль Openshift/K8S")
+	fmt.Fprintln(w, "  - OC_NAMESPACES            : myns1, myns2, myns3                    # (обязательно для okd-sync) Пространства имен через запятую")
+	fmt.Fprintln(w, "  - OC_CLUSTER               : mycluster                              # (обязательно для okd-sync) Имя кластера")
+
+	fmt.Fprintln(w, "\nДля Init/Unseal Vault:")
+	fmt.Fprintln(w, "  - VAULT_INIT_SHARES        : 5                                      # (не обязательно)(по умолчанию 5) Количество ключей для инициализации")
+	fmt.Fprintln(w, "  - VAULT_INIT_THRESHOLD     : 3                                      # (не обязательно)(по умолчанию 3) Количество ключей для успешной разблокировки")
+	fmt.Fprintln(w, "  - SEC_VAULT_UNSEAL_KEY*    : MYUNSEALKEY1                           # (обязательно для unseal) Ключи для успешной разблокировки")
+
+	fmt.Fprintln(w, "\nФайл конфигурации:")
+	fmt.Fprintln(w, "  - --config                 : hydra.yaml                             # (не обязательно) Путь к файлу конфигурации, также HYDRA_CONFIG. По умолчанию $CI_PROJECT_DIR/.hydra.yaml")
+	fmt.Fprintln(w, "  - --profile                : prod,okd-east                          # (не обязательно) Профили из файла через запятую, также HYDRA_PROFILE")
+	fmt.Fprintln(w, "  - Приоритет значений: флаг команды > переменная окружения > профиль > settings файла > значение по умолчанию")
+
+	fmt.Fprintln(w, "\nФлаги команд:")
+	fmt.Fprintln(w, "  - Большинство переменных можно передать флагом команды: --addr вместо VAULT_ADDR, --sec-addr вместо SEC_VAULT_ADDR, --path вместо VAULT_SECRET_PATH и т.д.")
+	fmt.Fprintln(w, "  - Токены, пароли и secret_id флагами не принимаются - используйте переменные окружения или *-file флаги")
+	fmt.Fprintln(w, "  - Полный список флагов команды: ./hydra <команда> --help")
+
+	fmt.Fprintln(w, "\nКоды завершения:")
+	fmt.Fprintln(w, "  - 0                        - команда выполнена успешно")
+	fmt.Fprintln(w, "  - 1                        - ошибка при выполнении команды")
+	fmt.Fprintln(w, "  - 2                        - неверные аргументы или не заданы обязательные настройки")
+	fmt.Fprintln(w, "  - 10                       - ошибка авторизации в Vault")
+	fmt.Fprintln(w, "  - 128+N                    - команда прервана сигналом N (130 для SIGINT, 143 для SIGTERM)")
+
+	fmt.Fprintln(w, "\nДополнительные заметки:")
+	fmt.Fprintln(w, "  - Убедитесь, что обязательные переменные окружения настроены правильно перед запуском операций Vault.")
+	fmt.Fprintln(w, "  - Используйте `VAULT_VERBOSE` для управления уровнем логирования (1 для ERROR, 2 для INFO, 3 для DEBUG). Логи и баннер пишутся в stderr.")
+	fmt.Fprintln(w, "  - Переменная SEC_VAULT_UNSEAL_KEY* начинается с SEC_VAULT_UNSEAL_KEY1 и заканчивается на SEC_VAULT_UNSEAL_KEY32.")
+	fmt.Fprintln(w, "  - **Общие переменные используются для обоих экземпляров Vault.")
 }
 func processKey(key string) string {
 	return strings.ToUpper(key)
@@ -424,7 +494,8 @@ func configureTLS(certsPath string, forHTTP bool) (*tls.Config, *vault.TLSConfig
 		if forHTTP {
 			return &tls.Config{InsecureSkipVerify: true}, nil, nil
 		}
-		return nil, &vault.TLSConfig{Insecure: true}, nil
+		vaultTLSConfig, err := applyClientCert(&vault.TLSConfig{Insecure: true})
+		return nil, vaultTLSConfig, err
 	}
 
 	// Выбор источника сертификата
@@ -454,50 +525,26 @@ func configureTLS(certsPath string, forHTTP bool) (*tls.Config, *vault.TLSConfig
 	if forHTTP {
 		return &tls.Config{RootCAs: rootCAs}, nil, nil
 	}
-	return nil, &vault.TLSConfig{CACertBytes: certData, Insecure: false}, nil
-}
-
-// Запрашивает clusterID экземпляра Vault
-func getClusterID(client *vault.Client) (string, error) {
-	// Выполняем запрос к эндпоинту sys/health
-	//req := client.NewRequest("GET", "/v1/sys/health")
-	resp, err := client.Logical().ReadRaw("/sys/health")
-	if err != nil {
-		return "", fmt.Errorf("ошибка при запросе /sys/health: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Декодируем ответ
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("ошибка декодирования ответа: %w", err)
-	}
-
-	// Получаем cluster_id
-	if clusterID, ok := result["cluster_id"].(string); ok {
-		return clusterID, nil
-	}
-
-	return "", fmt.Errorf("cluster_id не найден в ответе")
+	vaultTLSConfig, err := applyClientCert(&vault.TLSConfig{CACertBytes: certData, Insecure: false})
+	return nil, vaultTLSConfig, err
 }
 
-// Сравнивает clusterID у двух переданных клиентов - если одинаковые вернет true
-func checkUniqClusterID(clientSrc *vault.Client, clientDst *vault.Client) (bool, error) {
-	clusterIDDST, err := getClusterID(clientDst)
-	if err != nil {
-		HandleError(err, fmt.Sprintf("Ошибка при проверке ClusterID"), Error)
-		return true, err
+// applyClientCert добавляет клиентский сертификат и ключ (mTLS) в TLS конфигурацию Vault, если они заданы
+func applyClientCert(tlsConfig *vault.TLSConfig) (*vault.TLSConfig, error) {
+	if clientCertPath == "" && clientKeyPath == "" {
+		return tlsConfig, nil
 	}
-	clusterIDSRC, err := getClusterID(clientSrc)
-	if err != nil {
-		HandleError(err, fmt.Sprintf("Ошибка при проверке ClusterID"), Error)
-		return true, err
+	if clientCertPath == "" || clientKeyPath == "" {
+		return nil, fmt.Errorf("для mTLS необходимо задать и VAULT_CLIENT_CERT, и VAULT_CLIENT_KEY")
 	}
-	if clusterIDSRC == clusterIDDST {
-		Log(Error, "ClusterID вольтов не должны совпадать! проверьте переменные VAULT_ADDR SEC_VAULT_ADDR")
-		return true, nil
+	// Проверяем, что пара сертификат/ключ читается и соответствует друг другу
+	if _, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath); err != nil {
+		return nil, fmt.Errorf("не удалось загрузить клиентский сертификат %s и ключ %s: %v", clientCertPath, clientKeyPath, err)
 	}
-	return false, nil
+	Log(Debug, fmt.Sprintf("Используем клиентский сертификат %s", clientCertPath))
+	tlsConfig.ClientCert = clientCertPath
+	tlsConfig.ClientKey = clientKeyPath
+	return tlsConfig, nil
 }
 
 // filterString принимает строку и регулярное выражение исключения.
@@ -0,0 +1,387 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"hydra/pkg/hydra/vaulttest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// k8sTokenFile - путь к токену сервисного аккаунта относительно рабочей директории hydra
+const k8sTokenFile = "var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// newInjectServer поднимает Vault с секретами для проверок inject
+func newInjectServer(t *testing.T) *vaulttest.Server {
+	t.Helper()
+	server := vaulttest.NewServer(t)
+	server.Mount("legacy", 1)
+	server.Put("secret/app/db", map[string]interface{}{"USER": "app", "PASSWORD": "p1"})
+	server.Put("secret/app/api", map[string]interface{}{"TOKEN": "t1"})
+	server.Put("secret/app/pinned", map[string]interface{}{"VALUE": "old"})
+	server.Put("secret/app/pinned", map[string]interface{}{"VALUE": "new"})
+	server.Put("legacy/app/x", map[string]interface{}{"A": "1"})
+	server.AddLogin("kubernetes", "app", "k8s-jwt")
+	server.AddLogin("git", "ci", "gitlab-jwt")
+	server.AddLogin("jwt", "ci", "gitlab-jwt")
+	return server
+}
+
+func TestInject(t *testing.T) {
+	server := newInjectServer(t)
+	tests := []struct {
+		name     string
+		env      map[string]string
+		files    map[string]string // файлы относительно рабочей директории
+		wantCode int
+		want     map[string]string // ожидаемое содержимое файлов относительно рабочей директории
+	}{
+		{
+			name: "токен, KV v2 и KV v1",
+			env: map[string]string{
+				"VAULT_TOKEN":       server.RootToken(),
+				"VAULT_SECRET_PATH": "secret/app/db legacy/app/x",
+			},
+			want: map[string]string{"tmp/envs": "A=\"1\"\nPASSWORD=\"p1\"\nUSER=\"app\""},
+		},
+		{
+			name: "путь с data и префикс",
+			env: map[string]string{
+				"VAULT_TOKEN":       server.RootToken(),
+				"VAULT_SECRET_PATH": "secret/data/app/api:API_",
+			},
+			want: map[string]string{"tmp/envs": "API_TOKEN=\"t1\""},
+		},
+		{
+			name: "закрепленная версия",
+			env: map[string]string{
+				"VAULT_TOKEN":       server.RootToken(),
+				"VAULT_SECRET_PATH": "secret/app/pinned@1",
+			},
+			want: map[string]string{"tmp/envs": "VALUE=\"old\""},
+		},
+		{
+			name: "рекурсивный режим",
+			env: map[string]string{
+				"VAULT_TOKEN":       server.RootToken(),
+				"VAULT_SECRET_PATH": "secret/app",
+				"VAULT_RECURSIVE":   "true",
+			},
+			want: map[string]string{
+				"tmp/secret/app/api": "TOKEN=\"t1\"",
+				"tmp/secret/app/db":  "PASSWORD=\"p1\"\nUSER=\"app\"",
+			},
+		},
+		{
+			name: "kubernetes",
+			env: map[string]string{
+				"VAULT_K8S_AUTH":    "true",
+				"VAULT_AUTH_ROLE":   "app",
+				"VAULT_SECRET_PATH": "secret/app/api",
+			},
+			files: map[string]string{k8sTokenFile: "k8s-jwt"},
+			want:  map[string]string{"tmp/envs": "TOKEN=\"t1\""},
+		},
+		{
+			name: "ID токен GitLab",
+			env: map[string]string{
+				"VAULT_ID_TOKEN":    "gitlab-jwt",
+				"VAULT_AUTH_ROLE":   "ci",
+				"VAULT_SECRET_PATH": "secret/app/api",
+			},
+			want: map[string]string{"tmp/envs": "TOKEN=\"t1\""},
+		},
+		{
+			name: "ID токен с другим путем авторизации",
+			env: map[string]string{
+				"VAULT_ID_TOKEN":    "gitlab-jwt",
+				"VAULT_AUTH_ROLE":   "ci",
+				"VAULT_AUTH_URL":    "auth/jwt/login",
+				"VAULT_SECRET_PATH": "secret/app/api",
+			},
+			want: map[string]string{"tmp/envs": "TOKEN=\"t1\""},
+		},
+		{
+			name: "неверная роль",
+			env: map[string]string{
+				"VAULT_ID_TOKEN":    "gitlab-jwt",
+				"VAULT_AUTH_ROLE":   "other",
+				"VAULT_SECRET_PATH": "secret/app/api",
+			},
+			wantCode: exitAuth,
+			want:     map[string]string{"tmp/envs": ""},
+		},
+		{
+			name: "неизвестный токен",
+			env: map[string]string{
+				"VAULT_TOKEN":       "hvs.unknown",
+				"VAULT_SECRET_PATH": "secret/app/api",
+			},
+			wantCode: exitAuth,
+			want:     map[string]string{"tmp/envs": ""},
+		},
+		{
+			name: "файл секретов без Vault",
+			env: map[string]string{
+				"VAULT_ADDR":         "",
+				"HYDRA_SECRETS_FILE": "secrets.yaml",
+				"VAULT_SECRET_PATH":  "secret/app/db",
+			},
+			files: map[string]string{"secrets.yaml": "secret/app/db:\n  USER: file\n"},
+			want:  map[string]string{"tmp/envs": "USER=\"file\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeTestFile(t, filepath.Join(dir, name), content)
+			}
+			env := map[string]string{"VAULT_ADDR": server.URL, "CI_PROJECT_DIR": dir}
+			for name, value := range tt.env {
+				env[name] = value
+			}
+			code, stdout, stderr := runHydra(t, dir, env, "inject")
+			if code != tt.wantCode {
+				t.Fatalf("код завершения %d, ожидался %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			for name, want := range tt.want {
+				if got := readFile(t, filepath.Join(dir, name)); got != want {
+					t.Errorf("%s:\n%s\nожидалось:\n%s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// writeTestFile создает файл вместе с родительскими директориями
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gitlabStub записывает переменные, созданные через API GitLab
+type gitlabStub struct {
+	URL  string
+	mu   sync.Mutex
+	vars map[string]string
+}
+
+func newGitLabStub(t *testing.T) *gitlabStub {
+	t.Helper()
+	stub := &gitlabStub{vars: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/projects/42/variables" || r.Header.Get("PRIVATE-TOKEN") != "glpat" {
+			http.Error(w, `{"message":"404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stub.mu.Lock()
+		stub.vars[payload["key"]] = payload["value"]
+		stub.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+	stub.URL = server.URL
+	return stub
+}
+
+func (g *gitlabStub) Vars() map[string]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	vars := make(map[string]string, len(g.vars))
+	for key, value := range g.vars {
+		vars[key] = value
+	}
+	return vars
+}
+
+// unsealVars возвращает переменные SEC_VAULT_UNSEAL_KEY* и SEC_VAULT_TOKEN, которые init сохраняет для server
+func unsealVars(server *vaulttest.Server) map[string]string {
+	vars := map[string]string{"SEC_VAULT_TOKEN": server.RootToken()}
+	for i, key := range server.UnsealKeys() {
+		vars[unsealKeyName(i)] = key
+	}
+	return vars
+}
+
+func unsealKeyName(i int) string {
+	return "SEC_VAULT_UNSEAL_KEY" + strconv.Itoa(i+1)
+}
+
+func TestInit(t *testing.T) {
+	const writePath = "secret/hydra/unseal"
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		initialized bool // вторичный Vault уже инициализирован
+		wantCode    int
+		wantShares  int  // число ключей, 0 - вторичный Vault не должен быть инициализирован командой
+		wantWritten bool // ключи записаны в основной Vault
+		wantGitLab  bool // ключи добавлены в переменные GitLab
+	}{
+		{
+			name:        "ключи по умолчанию",
+			wantShares:  5,
+			wantWritten: true,
+			wantGitLab:  true,
+		},
+		{
+			name:        "число ключей из флагов",
+			args:        []string{"--shares", "3", "--threshold", "2"},
+			wantShares:  3,
+			wantWritten: true,
+			wantGitLab:  true,
+		},
+		{
+			name:        "без CI_API_V4_URL",
+			env:         map[string]string{"CI_API_V4_URL": ""},
+			wantCode:    exitError,
+			wantShares:  5,
+			wantWritten: true,
+		},
+		{
+			name:        "уже инициализирован",
+			initialized: true,
+			wantCode:    exitError,
+		},
+		{
+			name:     "неверный токен основного Vault",
+			env:      map[string]string{"VAULT_TOKEN": "hvs.unknown"},
+			wantCode: exitAuth,
+			// Инициализация выполняется до записи ключей
+			wantShares: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := vaulttest.NewServer(t)
+			sec := vaulttest.NewUninitializedServer(t)
+			if tt.initialized {
+				sec = vaulttest.NewServer(t)
+			}
+			gitlab := newGitLabStub(t)
+			env := map[string]string{
+				"VAULT_ADDR":       primary.URL,
+				"VAULT_TOKEN":      primary.RootToken(),
+				"SEC_VAULT_ADDR":   sec.URL,
+				"VAULT_WRITE_PATH": writePath,
+				"CI_API_V4_URL":    gitlab.URL,
+				"CI_PROJECT_ID":    "42",
+				"GITLAB_API_TOKEN": "glpat",
+			}
+			for name, value := range tt.env {
+				env[name] = value
+			}
+			dir := t.TempDir()
+			code, stdout, stderr := runHydra(t, dir, env, append([]string{"init"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("код завершения %d, ожидался %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			if tt.wantShares == 0 {
+				if tt.initialized && len(sec.UnsealKeys()) != 5 {
+					t.Errorf("ключи вторичного Vault изменились: %d", len(sec.UnsealKeys()))
+				}
+			} else {
+				if !sec.Initialized() || sec.Sealed() {
+					t.Fatalf("вторичный Vault: initialized %v, sealed %v", sec.Initialized(), sec.Sealed())
+				}
+				if got := len(sec.UnsealKeys()); got != tt.wantShares {
+					t.Errorf("ключей %d, ожидалось %d", got, tt.wantShares)
+				}
+			}
+			want := unsealVars(sec)
+			written := primary.Get(writePath)
+			if tt.wantWritten != (written != nil) {
+				t.Fatalf("ключи в основном Vault: %v", written)
+			}
+			for name, value := range written {
+				if want[name] != value {
+					t.Errorf("%s в основном Vault: %v, ожидалось %s", name, value, want[name])
+				}
+			}
+			if tt.wantWritten && len(written) != len(want) {
+				t.Errorf("в основной Vault записано %d значений, ожидалось %d", len(written), len(want))
+			}
+			vars := gitlab.Vars()
+			if !tt.wantGitLab {
+				want = map[string]string{}
+			}
+			if len(vars) != len(want) {
+				t.Errorf("переменные GitLab: %v, ожидалось %v", vars, want)
+			}
+			for name, value := range want {
+				if vars[name] != value {
+					t.Errorf("переменная GitLab %s: %q, ожидалось %q", name, vars[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestUnseal(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		keys       []int // номера ключей сервера, переданных в SEC_VAULT_UNSEAL_KEY1..N, -1 - неверный ключ
+		token      string
+		wantCode   int
+		wantSealed bool
+	}{
+		{name: "все ключи", keys: []int{0, 1, 2, 3, 4}},
+		{name: "порог ключей", args: []string{"--shares", "3"}, keys: []int{4, 0, 2}},
+		{name: "неверный ключ", args: []string{"--shares", "3"}, keys: []int{0, -1, 1}, wantCode: exitError, wantSealed: true},
+		{name: "нет ключей", wantCode: exitError, wantSealed: true},
+		{name: "неверный токен", keys: []int{0, 1, 2, 3, 4}, token: "hvs.unknown", wantCode: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sec := vaulttest.NewServer(t)
+			keys := sec.UnsealKeys()
+			sec.Seal()
+			env := map[string]string{
+				"SEC_VAULT_ADDR":   sec.URL,
+				"SEC_VAULT_TOKEN":  sec.RootToken(),
+				"VAULT_WRITE_PATH": "secret/hydra/unseal",
+			}
+			if tt.token != "" {
+				env["SEC_VAULT_TOKEN"] = tt.token
+			}
+			for i, key := range tt.keys {
+				value := "AAAA"
+				if key >= 0 {
+					value = keys[key]
+				}
+				env[unsealKeyName(i)] = value
+			}
+			code, stdout, stderr := runHydra(t, t.TempDir(), env, append([]string{"unseal"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("код завершения %d, ожидался %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			if sec.Sealed() != tt.wantSealed {
+				t.Errorf("sealed %v, ожидалось %v", sec.Sealed(), tt.wantSealed)
+			}
+		})
+	}
+}
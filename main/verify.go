@@ -0,0 +1,78 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hydra/pkg/hydra"
+	"io"
+	"strings"
+)
+
+// Форматы отчета hydra verify
+const (
+	verifyFormatText = "text"
+	verifyFormatJSON = "json"
+)
+
+// verifySecrets выполняет команду verify: сравнивает секреты по путям VAULT_BACKUP_PATH
+// в основном и вторичном Vault по хешу данных. Возвращает true, если найдены расхождения
+func verifySecrets(w io.Writer) (bool, error) {
+	source := secretStore(primaryConfig, secretsFile)
+	target := secretStore(secondaryConfig, SecSecretsFile)
+	report, err := hydra.Verify(context.Background(), source, target, hydra.VerifyOptions{
+		Paths:   strings.Fields(backupPath),
+		Exclude: excluded,
+	})
+	if err != nil {
+		return false, err
+	}
+	// В отчете адреса указываются так, как они заданы в настройках
+	report.Source = describeStore(primaryConfig, secretsFile)
+	report.Target = describeStore(secondaryConfig, SecSecretsFile)
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if file := setting("HYDRA_VERIFY_REPORT"); file != "" {
+		if err := writeFileAtomic(file, append(reportJSON, '\n'), 0644); err != nil {
+			return false, err
+		}
+		Log(Info, fmt.Sprintf("Отчет сверки записан в %s", file))
+	}
+	if setting("HYDRA_VERIFY_FORMAT") == verifyFormatJSON {
+		fmt.Fprintln(w, string(reportJSON))
+	} else {
+		printVerifyReport(w, report)
+	}
+	return report.Drift, nil
+}
+
+// printVerifyReport выводит отчет сверки текстом
+func printVerifyReport(w io.Writer, report *hydra.VerifyReport) {
+	fmt.Fprintf(w, "Сверка %s и %s, пути: %s\n", report.Source, report.Target, strings.Join(report.Paths, " "))
+	for _, path := range report.Missing {
+		fmt.Fprintf(w, "  %-9s %s\n", "missing", path)
+	}
+	for _, path := range report.Extra {
+		fmt.Fprintf(w, "  %-9s %s\n", "extra", path)
+	}
+	for _, path := range report.Different {
+		fmt.Fprintf(w, "  %-9s %s\n", "different", path)
+	}
+	fmt.Fprintf(w, "Проверено: %d, совпадает: %d, отсутствует: %d, лишних: %d, отличается: %d\n",
+		report.Checked, report.Matched, len(report.Missing), len(report.Extra), len(report.Different))
+	if report.Drift {
+		fmt.Fprintln(w, "Найдены расхождения")
+	} else {
+		fmt.Fprintln(w, "Расхождений нет")
+	}
+}
@@ -0,0 +1,165 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hydra/pkg/hydra"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// kvMetadata - метаданные секрета KV v2. В таком виде они также сохраняются в архив hydra export
+type kvMetadata = hydra.Metadata
+
+// splitVersion отделяет закрепленную версию от пути: myns/app/db@3 -> myns/app/db, 3.
+// Версия 0 означает последнюю. Версией считаются только цифры после последнего @, поэтому
+// пути с @ в имени (users/john@example.com) читаются как раньше
+func splitVersion(path string) (string, int, error) {
+	at := strings.LastIndex(path, "@")
+	if at < 0 || !isDigits(path[at+1:]) {
+		return path, 0, nil
+	}
+	version, err := strconv.Atoi(path[at+1:])
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("некорректная версия в пути '%s', ожидается путь@N, где N - номер версии", path)
+	}
+	return path[:at], version, nil
+}
+
+// isDigits сообщает, что строка - непустая последовательность цифр
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSecretPaths проверяет версии в VAULT_SECRET_PATH. В рекурсивном режиме
+// путь - это папка, у которой нет версий, поэтому закреплять версию нельзя
+func validateSecretPaths(specs string) error {
+	for _, spec := range strings.Fields(specs) {
+		path, _ := splitPathSpec(spec)
+		_, version, err := splitVersion(path)
+		if err != nil {
+			return err
+		}
+		if version > 0 && checkVaultRecursiveEnv() {
+			return fmt.Errorf("версию нельзя закрепить в рекурсивном режиме: %s", path)
+		}
+	}
+	return nil
+}
+
+// readSecretSpec читает секрет, учитывая закрепленную версию path@N.
+// Ошибки не логируются: секреты читаются параллельно, и вызывающий выводит их в порядке путей
+func readSecretSpec(client hydra.SecretStore, spec string) ([]string, error) {
+	path, version, err := splitVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return readSecret(client, path)
+	}
+	data, err := readSecretVersion(client, path, version)
+	if err != nil {
+		return nil, err
+	}
+	secretJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return []string{string(secretJSON)}, nil
+}
+
+// readSecretVersion читает указанную версию секрета KV v2
+func readSecretVersion(client hydra.SecretStore, path string, version int) (map[string]interface{}, error) {
+	return client.ReadVersion(context.Background(), path, version)
+}
+
+// readMetadata читает метаданные секрета KV v2 со списком версий
+func readMetadata(client hydra.SecretStore, path string) (*kvMetadata, error) {
+	return client.Metadata(context.Background(), path)
+}
+
+// printVersions выводит историю версий секрета таблицей
+func printVersions(w io.Writer, path string, metadata *kvMetadata) {
+	fmt.Fprintf(w, "Секрет: %s\n", path)
+	fmt.Fprintf(w, "Текущая версия: %d, самая старая: %d, max_versions: %d, изменен: %s\n",
+		metadata.CurrentVersion, metadata.OldestVersion, metadata.MaxVersions, metadata.UpdatedTime)
+	if len(metadata.CustomMetadata) > 0 {
+		keys := make([]string, 0, len(metadata.CustomMetadata))
+		for key := range metadata.CustomMetadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(w, "custom_metadata:")
+		for _, key := range keys {
+			fmt.Fprintf(w, "  %s = %v\n", key, metadata.CustomMetadata[key])
+		}
+	}
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ВЕРСИЯ\tСОЗДАНА\tСОСТОЯНИЕ\tУДАЛЕНА")
+	for _, version := range metadata.Versions {
+		marker := ""
+		if version.Version == metadata.CurrentVersion {
+			marker = " *"
+		}
+		deleted := version.DeletionTime
+		if deleted == "" {
+			deleted = "-"
+		}
+		fmt.Fprintf(table, "%d%s\t%s\t%s\t%s\n", version.Version, marker, version.CreatedTime, version.State(), deleted)
+	}
+	table.Flush()
+}
+
+// showVersions выполняет команду versions
+func showVersions(w io.Writer, path string) error {
+	client, err := auth(primaryConfig)
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+	}
+	metadata, err := readMetadata(libClient(client), path)
+	if err != nil {
+		return err
+	}
+	printVersions(w, strings.Trim(path, "/"), metadata)
+	return nil
+}
+
+// rollbackSecret записывает данные старой версии как новую последнюю версию.
+// Запись идет с check-and-set по текущей версии, чтобы не перезаписать изменение, сделанное во время отката
+func rollbackSecret(w io.Writer, path string, version int) error {
+	client, err := auth(primaryConfig)
+	if err != nil {
+		exitWith(exitAuth, fmt.Errorf("Ошибка при аутентификации: %w", err))
+	}
+	newVersion, err := libClient(client).Rollback(context.Background(), path, version)
+	if err != nil {
+		return err
+	}
+	if newVersion == version {
+		fmt.Fprintf(w, "Версия %d секрета %s уже последняя, откат не нужен\n", version, path)
+		return nil
+	}
+	fmt.Fprintf(w, "Секрет %s откатен к версии %d, новая версия: %d\n", path, version, newVersion)
+	return nil
+}
@@ -0,0 +1,36 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package main
+
+import "testing"
+
+func TestSplitVersion(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantPath    string
+		wantVersion int
+		wantErr     bool
+	}{
+		{spec: "myns/app/db", wantPath: "myns/app/db"},
+		{spec: "myns/app/db@3", wantPath: "myns/app/db", wantVersion: 3},
+		{spec: "users/john@example.com", wantPath: "users/john@example.com"},
+		{spec: "users/john@example.com@2", wantPath: "users/john@example.com", wantVersion: 2},
+		{spec: "myns/app/db@", wantPath: "myns/app/db@"},
+		{spec: "myns/app/db@0", wantErr: true},
+	}
+	for _, tt := range tests {
+		path, version, err := splitVersion(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitVersion(%q): %v, ожидается ошибка: %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if path != tt.wantPath || version != tt.wantVersion {
+			t.Errorf("splitVersion(%q) = %q, %d, ожидается %q, %d", tt.spec, path, version, tt.wantPath, tt.wantVersion)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"fmt"
+	vault "github.com/hashicorp/vault/api"
+	"strings"
+)
+
+// AuthOptions - способ авторизации в Vault. Используется первый заданный по приоритету:
+// Token, AppRole (RoleID), сертификат (Cert), логин и пароль (Username), JWT
+type AuthOptions struct {
+	// Token - готовый токен Vault. Проверяется через lookup-self, не продлевается и не отзывается
+	Token string
+
+	// AppRole
+	RoleID          string // role_id роли AppRole
+	SecretID        string // secret_id в открытом виде или wrapping токен
+	SecretIDWrapped bool   // secret_id передан как wrapping токен и его нужно развернуть
+	AppRolePath     string // Путь монтирования AppRole (по умолчанию approle)
+
+	// TLS сертификат: клиентский сертификат задается в Options.TLS
+	Cert     bool   // Авторизация по клиентскому сертификату
+	CertRole string // Имя роли cert auth (не обязательно)
+	CertPath string // Путь монтирования cert auth (по умолчанию cert)
+
+	// Userpass / LDAP
+	Username    string // Имя пользователя
+	Password    string // Пароль
+	LoginMethod string // Метод авторизации: userpass или ldap (по умолчанию userpass)
+	LoginPath   string // Путь монтирования метода (по умолчанию совпадает с LoginMethod)
+
+	// JWT: токен service account Kubernetes или ID токен CI
+	JWT     string // Токен
+	JWTPath string // Путь авторизации, например auth/kubernetes/login
+	Role    string // Роль Vault
+}
+
+// Login авторизуется способом из Options.Auth и подставляет полученный токен в клиент.
+// Возвращает ответ Vault с токеном, для готового токена Options.Auth.Token - nil.
+// Повторный вызов авторизуется заново, например когда токен больше нельзя продлить
+func (c *Client) Login(ctx context.Context) (*vault.Secret, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	auth := &c.opts.Auth
+
+	if auth.Token != "" {
+		c.vault.SetToken(auth.Token)
+		if _, err := c.vault.Logical().ReadWithContext(ctx, "auth/token/lookup-self"); err != nil {
+			return nil, fmt.Errorf("ошибка при проверке токена: %w", err)
+		}
+		return nil, nil
+	}
+
+	// Авторизация идет через копию клиента без токена, чтобы не отправлять старый токен
+	loginClient, err := c.vault.Clone()
+	if err != nil {
+		return nil, err
+	}
+	loginClient.ClearToken()
+	if namespace := c.vault.Namespace(); namespace != "" {
+		loginClient.SetNamespace(namespace)
+	}
+
+	var loginResp *vault.Secret
+	switch {
+	case auth.RoleID != "":
+		loginResp, err = c.appRoleLogin(ctx, loginClient, auth)
+	case auth.Cert:
+		loginResp, err = c.certLogin(ctx, loginClient, auth)
+	case auth.Username != "":
+		loginResp, err = c.passwordLogin(ctx, loginClient, auth)
+	case auth.JWT != "":
+		loginResp, err = c.jwtLogin(ctx, loginClient, auth)
+	default:
+		return nil, fmt.Errorf("не выбран токен для аутентификации: не задан ни один из способов авторизации")
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.vault.SetToken(loginResp.Auth.ClientToken)
+	return loginResp, nil
+}
+
+// jwtLogin авторизуется по K8s или ID токену
+func (c *Client) jwtLogin(ctx context.Context, client *vault.Client, auth *AuthOptions) (*vault.Secret, error) {
+	if auth.JWTPath == "" {
+		return nil, fmt.Errorf("не задан путь авторизации по JWT")
+	}
+	loginResp, err := client.Logical().WriteWithContext(ctx, auth.JWTPath, map[string]interface{}{
+		"jwt":  auth.JWT,
+		"role": auth.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkLoginResponse(loginResp, auth.JWTPath)
+}
+
+// checkLoginResponse проверяет, что ответ на авторизацию содержит токен
+func checkLoginResponse(loginResp *vault.Secret, loginPath string) (*vault.Secret, error) {
+	if loginResp == nil || loginResp.Auth == nil || loginResp.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("пустой ответ при авторизации по пути %s", loginPath)
+	}
+	return loginResp, nil
+}
+
+// appRoleLogin авторизуется по role_id и secret_id
+func (c *Client) appRoleLogin(ctx context.Context, client *vault.Client, auth *AuthOptions) (*vault.Secret, error) {
+	if auth.SecretID == "" {
+		return nil, fmt.Errorf("не задан secret_id для роли %s", auth.RoleID)
+	}
+	if auth.SecretIDWrapped {
+		secretID, err := c.unwrapSecretID(ctx, client, auth.SecretID)
+		if err != nil {
+			return nil, err
+		}
+		// Wrapping токен одноразовый - сохраняем развернутый secret_id для повторной авторизации
+		auth.SecretID = secretID
+		auth.SecretIDWrapped = false
+	}
+
+	appRolePath := strings.Trim(auth.AppRolePath, "/")
+	if appRolePath == "" {
+		appRolePath = "approle"
+	}
+	loginPath := fmt.Sprintf("auth/%s/login", appRolePath)
+	c.logf(LogInfo, "Авторизуемся через AppRole по пути %s", loginPath)
+
+	loginResp, err := client.Logical().WriteWithContext(ctx, loginPath, map[string]interface{}{
+		"role_id":   auth.RoleID,
+		"secret_id": auth.SecretID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkLoginResponse(loginResp, loginPath)
+}
+
+// unwrapSecretID разворачивает secret_id, используя wrapping токен как клиентский токен
+func (c *Client) unwrapSecretID(ctx context.Context, client *vault.Client, wrappingToken string) (string, error) {
+	c.logf(LogDebug, "Разворачиваем wrapping токен secret_id")
+	client.SetToken(wrappingToken)
+	defer client.ClearToken()
+	unwrapped, err := client.Logical().UnwrapWithContext(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("не удалось развернуть wrapping токен secret_id: %w", err)
+	}
+	if unwrapped == nil || unwrapped.Data == nil {
+		return "", fmt.Errorf("wrapping токен не содержит данных")
+	}
+	secretID, ok := unwrapped.Data["secret_id"].(string)
+	if !ok || secretID == "" {
+		return "", fmt.Errorf("в развернутом ответе отсутствует secret_id")
+	}
+	return secretID, nil
+}
+
+// certLogin авторизуется по клиентскому сертификату, который клиент предъявляет при TLS соединении
+func (c *Client) certLogin(ctx context.Context, client *vault.Client, auth *AuthOptions) (*vault.Secret, error) {
+	if c.opts.TLS == nil || c.opts.TLS.ClientCert == "" {
+		return nil, fmt.Errorf("для авторизации по сертификату необходимо задать VAULT_CLIENT_CERT и VAULT_CLIENT_KEY")
+	}
+
+	certPath := strings.Trim(auth.CertPath, "/")
+	if certPath == "" {
+		certPath = "cert"
+	}
+	loginPath := fmt.Sprintf("auth/%s/login", certPath)
+	c.logf(LogInfo, "Авторизуемся по сертификату по пути %s", loginPath)
+
+	var data map[string]interface{}
+	if auth.CertRole != "" {
+		data = map[string]interface{}{"name": auth.CertRole}
+	}
+	loginResp, err := client.Logical().WriteWithContext(ctx, loginPath, data)
+	if err != nil {
+		return nil, err
+	}
+	return checkLoginResponse(loginResp, loginPath)
+}
+
+// passwordLogin авторизуется по логину и паролю через userpass или ldap
+func (c *Client) passwordLogin(ctx context.Context, client *vault.Client, auth *AuthOptions) (*vault.Secret, error) {
+	loginMethod := strings.ToLower(auth.LoginMethod)
+	if loginMethod == "" {
+		loginMethod = "userpass"
+	}
+	if loginMethod != "userpass" && loginMethod != "ldap" {
+		return nil, fmt.Errorf("неизвестный метод авторизации %s, ожидается userpass или ldap", auth.LoginMethod)
+	}
+	if auth.Password == "" {
+		return nil, fmt.Errorf("не задан пароль для пользователя %s", auth.Username)
+	}
+	loginMount := strings.Trim(auth.LoginPath, "/")
+	if loginMount == "" {
+		loginMount = loginMethod
+	}
+
+	loginPath := fmt.Sprintf("auth/%s/login/%s", loginMount, auth.Username)
+	c.logf(LogInfo, "Авторизуемся через %s по пути %s", loginMethod, loginPath)
+	loginResp, err := client.Logical().WriteWithContext(ctx, loginPath, map[string]interface{}{
+		"password": auth.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkLoginResponse(loginResp, loginPath)
+}
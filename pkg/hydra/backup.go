@@ -0,0 +1,532 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Режимы резервного копирования
+const (
+	BackupFull        = "full"        // engine пересоздается и все секреты копируются заново
+	BackupIncremental = "incremental" // копируются только изменившиеся секреты, engine не удаляется
+)
+
+// Суффиксы временных engine при копировании через staging
+const (
+	stagingMountSuffix = "-hydra-staging"
+	retiredMountSuffix = "-hydra-old"
+)
+
+// Секрет в корне engine копии с версиями секретов источника: путь -> current_version/updated_time[/history].
+// По нему следующий запуск понимает, что секрет не менялся, не читая его данные. Состояние хранится
+// отдельно от секретов, чтобы не смешивать его с custom_metadata, Walk этот секрет не возвращает
+const backupStateName = ".hydra-backup-state"
+
+// Действия над секретом копии
+const (
+	actionCreate    = "create"
+	actionUpdate    = "update"
+	actionUnchanged = "unchanged"
+)
+
+// BackupOptions - параметры резервного копирования
+type BackupOptions struct {
+	Paths   []string               // Копируемые пути, * - все engine KV источника
+	Mode    string                 // BackupFull (по умолчанию) или BackupIncremental
+	Prune   bool                   // Удалять из копии секреты, которых нет в источнике (только incremental)
+	Staging bool                   // Писать в отдельный engine и заменять им копию после успешного запуска (только incremental)
+	History bool                   // Копировать секреты KV v2 со всеми версиями, метаданными и настройками engine
+	Exclude func(path string) bool // Секреты, для которых возвращается true, не копируются; nil - копируются все
+}
+
+// BackupSummary - итог копирования одного engine
+type BackupSummary struct {
+	Mount     string
+	Created   int
+	Updated   int
+	Unchanged int
+	Deleted   int
+}
+
+// count учитывает действие над секретом в итоге
+func (s *BackupSummary) count(action string) {
+	switch action {
+	case actionCreate:
+		s.Created++
+	case actionUpdate:
+		s.Updated++
+	default:
+		s.Unchanged++
+	}
+}
+
+// Backup копирует секреты из src в dst по engine. Между двумя Vault источник и копия должны быть разными Vault
+// или разными namespace, иначе возвращается ErrSameVault. Если хотя бы одно из хранилищ не Vault,
+// секреты копируются без пересоздания engine, Staging и History в этом случае не поддерживаются.
+// При ошибке возвращаются итоги по уже скопированным engine
+func Backup(ctx context.Context, src, dst SecretStore, opts BackupOptions) ([]*BackupSummary, error) {
+	if opts.Mode != "" && opts.Mode != BackupFull && opts.Mode != BackupIncremental {
+		return nil, fmt.Errorf("неизвестный режим резервного копирования: %s", opts.Mode)
+	}
+	srcClient, srcVault := src.(*Client)
+	dstClient, dstVault := dst.(*Client)
+	switch {
+	case src == dst:
+		return nil, ErrSameVault
+	case srcVault && dstVault:
+		if err := checkDistinct(ctx, srcClient, dstClient); err != nil {
+			return nil, err
+		}
+	case opts.Staging || opts.History:
+		return nil, fmt.Errorf("staging и history поддерживаются только при копировании между Vault")
+	}
+	mounts, err := storeMounts(ctx, src, opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+	var summaries []*BackupSummary
+	for _, mount := range mounts {
+		var summary *BackupSummary
+		switch {
+		case !srcVault || !dstVault:
+			summary, err = storeBackup(ctx, src, dst, mount, opts)
+		case opts.Mode == BackupIncremental:
+			summary, err = incrementalBackup(ctx, srcClient, dstClient, mount, opts)
+		default:
+			summary, err = fullBackup(ctx, srcClient, dstClient, mount, opts)
+		}
+		if err != nil {
+			return summaries, fmt.Errorf("ошибка при резервном копировании engine '%s': %w", mount.Name, err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// included возвращает пути, не исключенные opts.Exclude
+func (opts BackupOptions) included(log LogFunc, paths []string) []string {
+	return filterExcluded(log, opts.Exclude, paths)
+}
+
+// filterExcluded убирает из списка пути, для которых exclude возвращает true
+func filterExcluded(log LogFunc, exclude func(string) bool, paths []string) []string {
+	if exclude == nil {
+		return paths
+	}
+	var result []string
+	for _, path := range paths {
+		if exclude(path) {
+			log.logf(LogInfo, "путь '%s' был исключен на основе регулярного выражения", path)
+			continue
+		}
+		result = append(result, path)
+	}
+	return result
+}
+
+// fullBackup пересоздает engine копии и копирует в него все секреты из путей mount.Roots
+func fullBackup(ctx context.Context, src, dst *Client, mount BackupMount, opts BackupOptions) (*BackupSummary, error) {
+	summary := &BackupSummary{Mount: mount.Name}
+	paths, err := walkRoots(ctx, src, mount.Roots)
+	if err != nil {
+		return nil, err
+	}
+	input, err := src.backupMountInput(ctx, mount.Name, opts.History)
+	if err != nil {
+		return nil, err
+	}
+	if err := dst.recreateMount(ctx, mount.Name, input); err != nil {
+		return nil, err
+	}
+	if opts.History {
+		if err := copyMountSettings(ctx, src, mount.Name, dst, mount.Name, input, false); err != nil {
+			return nil, err
+		}
+	}
+	included := opts.included(src.opts.Log, paths)
+	// Секреты копируются параллельно, не больше Options.Concurrency одновременно
+	_, err = parallel(ctx, src, included, func(ctx context.Context, path string) (bool, error) {
+		if opts.History && checkPath(path, mount.Name) {
+			if err := replaySecret(ctx, src, path, dst, path); err != nil {
+				return false, fmt.Errorf("ошибка при копировании истории секрета по пути %s: %w", path, err)
+			}
+			return true, nil
+		}
+		data, err := src.Read(ctx, path)
+		if err != nil {
+			return false, fmt.Errorf("ошибка при получении секрета по пути %s: %w", path, err)
+		}
+		if err := dst.Write(ctx, path, data); err != nil {
+			return false, fmt.Errorf("ошибка при записи секрета по пути %s: %w", path, err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	summary.Created = len(included)
+	return summary, nil
+}
+
+// incrementalBackup сравнивает секреты источника и копии и записывает только изменения.
+// Engine копии не удаляется: при ошибке в середине остается предыдущая копия с частью обновлений.
+// Со staging все секреты пишутся в отдельный engine, который заменяет копию только после успешного запуска
+func incrementalBackup(ctx context.Context, src, dst *Client, backup BackupMount, opts BackupOptions) (*BackupSummary, error) {
+	mount := backup.Name
+	summary := &BackupSummary{Mount: mount}
+
+	srcPaths, err := walkRoots(ctx, src, backup.Roots)
+	if err != nil {
+		return nil, err
+	}
+	input, err := src.backupMountInput(ctx, mount, opts.History)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := dst.ensureMount(ctx, mount, input)
+	if err != nil {
+		return nil, err
+	}
+	if opts.History && !opts.Staging {
+		if err := copyMountSettings(ctx, src, mount, dst, mount, input, exists); err != nil {
+			return nil, err
+		}
+	}
+	dstPaths, err := walkRoots(ctx, dst, backup.Roots)
+	if err != nil {
+		return nil, err
+	}
+
+	target := mount
+	if opts.Staging {
+		target = mount + stagingMountSuffix
+		if err := dst.createStagingMount(ctx, target, input); err != nil {
+			return nil, err
+		}
+		if opts.History {
+			if err := copyMountSettings(ctx, src, mount, dst, target, input, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	state, err := readData(ctx, dst, mount+"/"+backupStateName)
+	if err != nil {
+		return nil, err
+	}
+	copier := &backupSync{src: src, dst: dst, mount: mount, target: target, history: opts.History,
+		state: state, present: map[string]bool{}, markers: map[string]interface{}{}}
+	for _, path := range dstPaths {
+		copier.present[NormalizePath(path)] = true
+	}
+
+	included := opts.included(src.opts.Log, srcPaths)
+	seen := map[string]bool{}
+	for _, path := range included {
+		seen[NormalizePath(path)] = true
+	}
+	actions, err := parallel(ctx, src, included, func(ctx context.Context, path string) (string, error) {
+		logical := NormalizePath(path)
+		action, err := copier.secret(ctx, path, logical)
+		if err != nil {
+			return "", fmt.Errorf("ошибка при копировании %s: %w (%s)", logical, err, describeStaging(opts.Staging, mount))
+		}
+		return action, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, action := range actions {
+		if action != actionUnchanged {
+			src.logf(LogInfo, "Секрет %s скопирован (%s)", NormalizePath(included[i]), action)
+		}
+		summary.count(action)
+	}
+
+	// Секреты, которых больше нет в источнике: в staging переносится все, что должно остаться в копии
+	var carried, deleted []string
+	for _, path := range dstPaths {
+		logical := NormalizePath(path)
+		switch {
+		case seen[logical]:
+		case !opts.Prune || (opts.Exclude != nil && opts.Exclude(path)):
+			if opts.Staging {
+				carried = append(carried, logical)
+			}
+		default:
+			deleted = append(deleted, logical)
+		}
+	}
+	if _, err := parallel(ctx, dst, carried, func(ctx context.Context, logical string) (bool, error) {
+		return true, copier.carry(ctx, logical)
+	}); err != nil {
+		return nil, err
+	}
+	if !opts.Staging {
+		if _, err := parallel(ctx, dst, deleted, func(ctx context.Context, logical string) (bool, error) {
+			return true, dst.Delete(ctx, logical)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, logical := range deleted {
+		dst.logf(LogInfo, "Секрет %s удален из копии: его нет в источнике", logical)
+		summary.Deleted++
+	}
+	if isKVv2Mount(input) {
+		if err := copier.saveState(ctx, deleted); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Staging {
+		// Секреты engine вне копируемых путей тоже переносятся, иначе замена engine их удалит
+		if !underAnyRoot(mount, backup.Roots) {
+			all, err := dst.Walk(ctx, mount)
+			if err != nil {
+				return nil, err
+			}
+			var outside []string
+			for _, path := range all {
+				if logical := NormalizePath(path); !underAnyRoot(logical, backup.Roots) {
+					outside = append(outside, logical)
+				}
+			}
+			if _, err := parallel(ctx, dst, outside, func(ctx context.Context, logical string) (bool, error) {
+				return true, copier.carry(ctx, logical)
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if err := dst.swapStagingMount(ctx, mount, target); err != nil {
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+// storeBackup копирует секреты из путей mount.Roots, когда хотя бы одно из хранилищ не Vault.
+// Engine пересоздать нельзя, поэтому в режиме full из копии удаляются все секреты engine, которых
+// нет в источнике, а в режиме incremental - только с Prune и только внутри копируемых путей
+func storeBackup(ctx context.Context, src, dst SecretStore, mount BackupMount, opts BackupOptions) (*BackupSummary, error) {
+	log := storeLog(src, dst)
+	summary := &BackupSummary{Mount: mount.Name}
+	srcPaths, err := walkRoots(ctx, src, mount.Roots)
+	if err != nil {
+		return nil, err
+	}
+	full := opts.Mode != BackupIncremental
+	dstRoots := mount.Roots
+	if full {
+		dstRoots = []string{mount.Name}
+	}
+	dstPaths, err := walkRoots(ctx, dst, dstRoots)
+	if err != nil {
+		return nil, err
+	}
+
+	included := opts.included(log, srcPaths)
+	seen := map[string]bool{}
+	for _, path := range included {
+		seen[NormalizePath(path)] = true
+	}
+	actions, err := RunParallel(ctx, storeConcurrency(dst), included, func(ctx context.Context, path string) (string, error) {
+		logical := NormalizePath(path)
+		data, err := readData(ctx, src, path)
+		if err != nil {
+			return "", fmt.Errorf("ошибка при получении секрета по пути %s: %w", path, err)
+		}
+		action := actionCreate
+		if existing, err := readData(ctx, dst, logical); err != nil {
+			return "", err
+		} else if existing != nil {
+			action = actionUpdate
+			if SameData(existing, data) {
+				return actionUnchanged, nil
+			}
+		}
+		if err := dst.Write(ctx, logical, data); err != nil {
+			return "", fmt.Errorf("ошибка при записи секрета по пути %s: %w", logical, err)
+		}
+		return action, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, action := range actions {
+		if action != actionUnchanged {
+			log.logf(LogInfo, "Секрет %s скопирован (%s)", NormalizePath(included[i]), action)
+		}
+		summary.count(action)
+	}
+
+	var deleted []string
+	for _, path := range dstPaths {
+		logical := NormalizePath(path)
+		if seen[logical] {
+			continue
+		}
+		if full || (opts.Prune && (opts.Exclude == nil || !opts.Exclude(path))) {
+			deleted = append(deleted, logical)
+		}
+	}
+	if _, err := RunParallel(ctx, storeConcurrency(dst), deleted, func(ctx context.Context, logical string) (bool, error) {
+		return true, dst.Delete(ctx, logical)
+	}); err != nil {
+		return nil, err
+	}
+	for _, logical := range deleted {
+		log.logf(LogInfo, "Секрет %s удален из копии: его нет в источнике", logical)
+		summary.Deleted++
+	}
+	return summary, nil
+}
+
+// describeStaging поясняет в ошибке, в каком состоянии осталась копия
+func describeStaging(staging bool, mount string) string {
+	if staging {
+		return fmt.Sprintf("engine копии %s не изменен, незавершенная копия осталась в %s", mount, mount+stagingMountSuffix)
+	}
+	return fmt.Sprintf("в engine копии %s остались уже записанные изменения", mount)
+}
+
+// backupSync копирует секреты из источника в engine target копии
+type backupSync struct {
+	src, dst *Client
+	mount    string                 // engine копии
+	target   string                 // engine, в который идет запись: сам engine копии или staging
+	history  bool                   // секреты KV v2 копируются со всеми версиями и метаданными
+	state    map[string]interface{} // состояние предыдущего запуска из backupStateName
+	present  map[string]bool        // секреты, которые уже есть в копии
+	mu       sync.Mutex
+	markers  map[string]interface{} // версии источника, записанные в этом запуске
+}
+
+// targetPath переводит путь секрета в engine, в который идет запись
+func (b *backupSync) targetPath(logical string) string {
+	return b.target + strings.TrimPrefix(logical, b.mount)
+}
+
+// secret копирует один секрет, если он изменился. Для KV v2 сначала сравнивается версия
+// источника с маркером в копии, данные читаются только если версии отличаются.
+// С историей изменившийся секрет копируется заново со всеми версиями
+func (b *backupSync) secret(ctx context.Context, srcPath, logical string) (string, error) {
+	marker := ""
+	if checkPath(srcPath, b.mount) {
+		metadata, err := b.src.Metadata(ctx, srcPath)
+		if err != nil {
+			return "", err
+		}
+		marker = fmt.Sprintf("%d/%s", metadata.CurrentVersion, metadata.UpdatedTime)
+		if b.history {
+			// Копия без истории при первом запуске с историей копируется заново
+			marker += "/history"
+		}
+	}
+	if marker != "" && b.present[logical] && b.state[logical] == marker {
+		b.dst.logf(LogDebug, "Секрет %s не менялся (версия %s)", logical, marker)
+		b.mark(logical, marker)
+		return actionUnchanged, b.carry(ctx, logical)
+	}
+
+	if b.history && marker != "" {
+		action := actionCreate
+		if existing, err := readData(ctx, b.dst, logical); err != nil {
+			return "", err
+		} else if existing != nil {
+			action = actionUpdate
+		}
+		if err := replaySecret(ctx, b.src, srcPath, b.dst, b.targetPath(logical)); err != nil {
+			return "", err
+		}
+		b.mark(logical, marker)
+		return action, nil
+	}
+
+	data, err := readData(ctx, b.src, srcPath)
+	if err != nil {
+		return "", err
+	}
+	action := actionCreate
+	if existing, err := readData(ctx, b.dst, logical); err != nil {
+		return "", err
+	} else if existing != nil {
+		action = actionUpdate
+		if SameData(existing, data) {
+			action = actionUnchanged
+		}
+	}
+	if action != actionUnchanged || b.target != b.mount {
+		if err := b.dst.Write(ctx, b.targetPath(logical), data); err != nil {
+			return "", err
+		}
+	}
+	if marker != "" {
+		b.mark(logical, marker)
+	}
+	return action, nil
+}
+
+// mark запоминает версию источника, с которой совпадает секрет копии
+func (b *backupSync) mark(logical, marker string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.markers[logical] = marker
+}
+
+// saveState записывает состояние копии в engine target: версии источника из этого запуска,
+// а для остальных секретов - из предыдущего, кроме удаленных из копии
+func (b *backupSync) saveState(ctx context.Context, deleted []string) error {
+	state := map[string]interface{}{}
+	for logical, marker := range b.state {
+		state[logical] = marker
+	}
+	for logical, marker := range b.markers {
+		state[logical] = marker
+	}
+	for _, logical := range deleted {
+		delete(state, logical)
+	}
+	if len(state) == 0 && b.state == nil {
+		return nil
+	}
+	// cas нужен, если в engine копии включен cas_required
+	path := b.target + "/" + backupStateName
+	current := 0
+	if metadata, err := b.dst.Metadata(ctx, path); err == nil {
+		current = metadata.CurrentVersion
+	}
+	_, err := b.dst.vault.Logical().WriteWithContext(ctx, KVv2Path(path, b.target, "data"), map[string]interface{}{
+		"options": map[string]interface{}{"cas": current},
+		"data":    state,
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось записать состояние копии %s: %w", path, err)
+	}
+	return nil
+}
+
+// carry переносит секрет из копии в staging без изменений, без staging ничего не делает
+func (b *backupSync) carry(ctx context.Context, logical string) error {
+	if b.target == b.mount {
+		return nil
+	}
+	if b.history {
+		if _, err := b.dst.Metadata(ctx, logical); err == nil {
+			return replaySecret(ctx, b.dst, logical, b.dst, b.targetPath(logical))
+		}
+	}
+	data, err := readData(ctx, b.dst, logical)
+	if err != nil || data == nil {
+		return err
+	}
+	return b.dst.Write(ctx, b.targetPath(logical), data)
+}
@@ -0,0 +1,326 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hydra/pkg/hydra/vaulttest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    BackupOptions
+		seedDst func(dst *vaulttest.Server)
+		want    []BackupSummary
+		wantDst map[string][]string // engine копии -> пути секретов после копирования
+	}{
+		{
+			name: "full все engine",
+			opts: BackupOptions{Paths: []string{"*"}, Mode: BackupFull},
+			seedDst: func(dst *vaulttest.Server) {
+				dst.Put("secret/stale", map[string]interface{}{"old": "1"})
+			},
+			want: []BackupSummary{{Mount: "legacy", Created: 2}, {Mount: "secret", Created: 4}},
+			wantDst: map[string][]string{
+				"legacy": {"legacy/app/x", "legacy/database"},
+				"secret": {"secret/app/api", "secret/app/db", "secret/database", "secret/team/x/y"},
+			},
+		},
+		{
+			name: "incremental без prune",
+			opts: BackupOptions{Paths: []string{"secret/app"}, Mode: BackupIncremental},
+			seedDst: func(dst *vaulttest.Server) {
+				dst.Put("secret/app/db", map[string]interface{}{"user": "app", "password": "p1"})
+				dst.Put("secret/app/api", map[string]interface{}{"token": "old"})
+				dst.Put("secret/app/stale", map[string]interface{}{"old": "1"})
+			},
+			want:    []BackupSummary{{Mount: "secret", Updated: 1, Unchanged: 1}},
+			wantDst: map[string][]string{"secret": {"secret/.hydra-backup-state", "secret/app/api", "secret/app/db", "secret/app/stale"}},
+		},
+		{
+			name: "incremental с prune и исключением",
+			opts: BackupOptions{Paths: []string{"secret"}, Mode: BackupIncremental, Prune: true, Exclude: func(path string) bool {
+				return strings.Contains(path, "team")
+			}},
+			seedDst: func(dst *vaulttest.Server) {
+				dst.Put("secret/app/db", map[string]interface{}{"user": "app", "password": "p1"})
+				dst.Put("secret/app/stale", map[string]interface{}{"old": "1"})
+				dst.Put("secret/team/kept", map[string]interface{}{"old": "1"})
+			},
+			want:    []BackupSummary{{Mount: "secret", Created: 2, Unchanged: 1, Deleted: 1}},
+			wantDst: map[string][]string{"secret": {"secret/.hydra-backup-state", "secret/app/api", "secret/app/db", "secret/database", "secret/team/kept"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newTestServer(t)
+			dst := vaulttest.NewServer(t)
+			if tt.seedDst != nil {
+				tt.seedDst(dst)
+			}
+			summaries, err := Backup(context.Background(), newTestClient(t, src), newTestClient(t, dst), tt.opts)
+			if err != nil {
+				t.Fatalf("Backup: %v", err)
+			}
+			got := make([]BackupSummary, len(summaries))
+			for i, summary := range summaries {
+				got[i] = *summary
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("итоги: %+v, ожидается %+v", got, tt.want)
+			}
+			for mount, want := range tt.wantDst {
+				if paths := dst.Paths(mount); !reflect.DeepEqual(paths, want) {
+					t.Errorf("секреты %s в копии: %v, ожидается %v", mount, paths, want)
+				}
+				for _, path := range want {
+					if data := src.Get(path); data != nil && !reflect.DeepEqual(dst.Get(path), data) {
+						t.Errorf("секрет %s в копии: %v, ожидается %v", path, dst.Get(path), data)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBackupIncrementalState(t *testing.T) {
+	ctx := context.Background()
+	src := newTestServer(t)
+	src.Put("secret/app/db", map[string]interface{}{"user": "app", "password": "p2"})
+	srcClient := newTestClient(t, src)
+	if _, err := srcClient.Vault().Logical().Write("secret/metadata/app/api", map[string]interface{}{"custom_metadata": map[string]interface{}{"owner": "team"}}); err != nil {
+		t.Fatal(err)
+	}
+	dst := vaulttest.NewServer(t)
+	dstClient := newTestClient(t, dst)
+
+	for _, history := range []bool{false, true} {
+		opts := BackupOptions{Paths: []string{"secret"}, Mode: BackupIncremental, History: history}
+		if _, err := Backup(ctx, srcClient, dstClient, opts); err != nil {
+			t.Fatalf("Backup: %v", err)
+		}
+		// Второй запуск узнает неизменившиеся секреты по состоянию копии
+		summaries, err := Backup(ctx, srcClient, dstClient, opts)
+		if err != nil {
+			t.Fatalf("Backup: %v", err)
+		}
+		if want := (BackupSummary{Mount: "secret", Unchanged: 4}); len(summaries) != 1 || *summaries[0] != want {
+			t.Errorf("history=%v: повторный запуск: %+v, ожидается %+v", history, summaries, want)
+		}
+		// Состояние не попадает в custom_metadata секретов и не считается лишним секретом при сверке
+		for _, path := range []string{"secret/app/db", "secret/app/api"} {
+			want, _ := srcClient.Metadata(ctx, path)
+			got, err := dstClient.Metadata(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.CustomMetadata) > 0 && !reflect.DeepEqual(got.CustomMetadata, want.CustomMetadata) {
+				t.Errorf("history=%v: custom_metadata %s в копии: %v", history, path, got.CustomMetadata)
+			}
+		}
+		report, err := Verify(ctx, srcClient, dstClient, VerifyOptions{Paths: []string{"secret"}})
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if report.Drift {
+			t.Errorf("history=%v: расхождения после копии: %+v", history, report)
+		}
+	}
+}
+
+func TestNestedMount(t *testing.T) {
+	ctx := context.Background()
+	src := vaulttest.NewServer(t)
+	src.Mount("team/kv", 2)
+	src.Mount("team/legacy", 1)
+	src.Put("team/kv/app/db", map[string]interface{}{"user": "app"})
+	src.Put("team/kv/app/api", map[string]interface{}{"token": "t1"})
+	src.Put("team/legacy/x", map[string]interface{}{"a": "1"})
+	client := newTestClient(t, src)
+
+	// Путь KV v2 строится от всей точки монтирования team/kv, а не от первого сегмента
+	if got, err := client.Read(ctx, "team/kv/app/db"); err != nil || got["user"] != "app" {
+		t.Fatalf("Read: %v, %v", got, err)
+	}
+	if err := client.Write(ctx, "team/kv/data/app/db", map[string]interface{}{"user": "new"}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := src.Versions("team/kv/app/db"); got != 2 {
+		t.Errorf("версий после записи: %d, ожидается 2", got)
+	}
+	if metadata, err := client.Metadata(ctx, "team/kv/app/db"); err != nil || metadata.CurrentVersion != 2 {
+		t.Errorf("Metadata: %+v, %v", metadata, err)
+	}
+	paths, err := client.Walk(ctx, "team/kv")
+	if want := []string{"team/kv/data/app/api", "team/kv/data/app/db"}; err != nil || !reflect.DeepEqual(paths, want) {
+		t.Errorf("Walk: %v, %v, ожидается %v", paths, err, want)
+	}
+
+	mounts, err := client.BackupMounts(ctx, []string{"team/kv/app", "team/legacy"})
+	if want := []BackupMount{{Name: "team/kv", Roots: []string{"team/kv/app"}}, {Name: "team/legacy", Roots: []string{"team/legacy"}}}; err != nil || !reflect.DeepEqual(mounts, want) {
+		t.Errorf("BackupMounts: %+v, %v, ожидается %+v", mounts, err, want)
+	}
+
+	dst := vaulttest.NewServer(t)
+	dstClient := newTestClient(t, dst)
+	for _, opts := range []BackupOptions{
+		{Paths: []string{"*"}, Mode: BackupFull},
+		{Paths: []string{"team/kv/app"}, Mode: BackupIncremental, Staging: true},
+	} {
+		if _, err := Backup(ctx, client, dstClient, opts); err != nil {
+			t.Fatalf("Backup %+v: %v", opts, err)
+		}
+		for _, path := range []string{"team/kv/app/db", "team/kv/app/api", "team/legacy/x"} {
+			if got, want := dst.Get(path), src.Get(path); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s в копии: %v, ожидается %v", path, got, want)
+			}
+		}
+	}
+	if got := dst.Paths("team"); got != nil {
+		t.Errorf("в копии создан engine team: %v", got)
+	}
+
+	// Хранилище без engine находит секрет вложенного engine по пути с data
+	store := NewMemoryStore(map[string]map[string]interface{}{"team/kv/app/db": {"user": "app"}})
+	if got, err := store.Read(ctx, "team/kv/data/app/db"); err != nil || got["user"] != "app" {
+		t.Errorf("MemoryStore.Read: %v, %v", got, err)
+	}
+}
+
+func TestBackupSameVault(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	_, err := Backup(context.Background(), client, newTestClient(t, server), BackupOptions{Paths: []string{"secret"}})
+	if !errors.Is(err, ErrSameVault) {
+		t.Fatalf("ожидается ErrSameVault, получено: %v", err)
+	}
+}
+
+func TestBackupToFileStore(t *testing.T) {
+	src := newTestServer(t)
+	file := filepath.Join(t.TempDir(), "backup.json")
+	dst, err := OpenFileStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := Backup(ctx, newTestClient(t, src), dst, BackupOptions{Paths: []string{"secret"}, Staging: true, Mode: BackupIncremental}); err == nil {
+		t.Fatal("staging в файл должен завершаться ошибкой")
+	}
+	summaries, err := Backup(ctx, newTestClient(t, src), dst, BackupOptions{Paths: []string{"secret"}})
+	if err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Created != 4 {
+		t.Fatalf("итоги: %+v, ожидается 4 созданных секрета", summaries)
+	}
+
+	// Файл перечитывается с диска и сверяется с источником
+	reopened, err := OpenFileStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Verify(ctx, newTestClient(t, src), reopened, VerifyOptions{Paths: []string{"secret"}})
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if report.Drift || report.Checked != 4 {
+		t.Errorf("сверка с файлом: %+v, ожидается 4 совпадающих секрета", report)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	src := newTestServer(t)
+	dst := NewMemoryStore(map[string]map[string]interface{}{
+		"secret/app/db":   {"user": "app", "password": "p1"},
+		"secret/app/api":  {"token": "changed"},
+		"secret/team/x/y": {"z": "1"},
+		"secret/extra":    {"a": "1"},
+	})
+	report, err := Verify(context.Background(), newTestClient(t, src), dst, VerifyOptions{Paths: []string{"secret"}})
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !report.Drift {
+		t.Error("ожидаются расхождения")
+	}
+	if want := []string{"secret/database"}; !reflect.DeepEqual(report.Missing, want) {
+		t.Errorf("missing: %v, ожидается %v", report.Missing, want)
+	}
+	if want := []string{"secret/extra"}; !reflect.DeepEqual(report.Extra, want) {
+		t.Errorf("extra: %v, ожидается %v", report.Extra, want)
+	}
+	if want := []string{"secret/app/api"}; !reflect.DeepEqual(report.Different, want) {
+		t.Errorf("different: %v, ожидается %v", report.Different, want)
+	}
+	if report.Matched != 2 {
+		t.Errorf("совпадает: %d, ожидается 2", report.Matched)
+	}
+}
+
+func TestBackupHistory(t *testing.T) {
+	ctx := context.Background()
+	src := vaulttest.NewServer(t)
+	srcClient := newTestClient(t, src)
+	write := func(path string, data map[string]interface{}) {
+		t.Helper()
+		if _, err := srcClient.Vault().Logical().Write(path, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 12 версий при max_versions engine по умолчанию: версии 1-2 удалены, oldest_version = 3
+	for i := 1; i <= 12; i++ {
+		src.Put("secret/app/db", map[string]interface{}{"value": i})
+	}
+	write("secret/destroy/app/db", map[string]interface{}{"versions": []int{5}})
+	write("secret/delete/app/db", map[string]interface{}{"versions": []int{7}})
+	// max_versions секрета больше, чем у engine: все 15 версий хранятся
+	write("secret/metadata/app/long", map[string]interface{}{"max_versions": 20, "custom_metadata": map[string]interface{}{"owner": "team"}})
+	for i := 1; i <= 15; i++ {
+		src.Put("secret/app/long", map[string]interface{}{"value": i})
+	}
+
+	dst := vaulttest.NewServer(t)
+	dstClient := newTestClient(t, dst)
+	if _, err := Backup(ctx, srcClient, dstClient, BackupOptions{Paths: []string{"secret"}, Mode: BackupFull, History: true}); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	for _, path := range []string{"secret/app/db", "secret/app/long"} {
+		want, err := srcClient.Metadata(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := dstClient.Metadata(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.CurrentVersion != want.CurrentVersion || got.MaxVersions != want.MaxVersions || !reflect.DeepEqual(got.CustomMetadata, want.CustomMetadata) {
+			t.Fatalf("%s: метаданные копии %+v, ожидается %+v", path, got, want)
+		}
+		for _, version := range want.Versions {
+			readable := !version.Destroyed && !version.Deleted()
+			data, err := dstClient.ReadVersion(ctx, path, version.Version)
+			switch {
+			case !readable && err == nil:
+				t.Errorf("%s: версия %d доступна в копии, ожидается состояние %s", path, version.Version, version.State())
+			case readable && err != nil:
+				t.Errorf("%s: версия %d: %v", path, version.Version, err)
+			case readable && !reflect.DeepEqual(data, map[string]interface{}{"value": json.Number(strconv.Itoa(version.Version))}):
+				t.Errorf("%s: версия %d копии: %v", path, version.Version, data)
+			}
+		}
+	}
+}
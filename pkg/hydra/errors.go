@@ -0,0 +1,22 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import "errors"
+
+// Ошибки, которые можно проверить через errors.Is
+var (
+	// ErrNotFound - секрет или версия секрета не найдены
+	ErrNotFound = errors.New("секрет не найден")
+	// ErrNotKVv2 - метаданные и версии есть только у секретов KV v2
+	ErrNotKVv2 = errors.New("версии есть только у секретов KV v2")
+	// ErrVersionUnavailable - версия секрета удалена или уничтожена
+	ErrVersionUnavailable = errors.New("версия удалена или уничтожена")
+	// ErrSameVault - источник и копия указывают на один и тот же Vault и namespace
+	ErrSameVault = errors.New("источник и копия - один и тот же Vault")
+)
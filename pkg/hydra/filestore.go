@@ -0,0 +1,123 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileStore - хранилище секретов в локальном файле JSON или YAML. Ключи верхнего уровня - пути
+// секретов, значения - данные секретов:
+//
+//	myns/app/db:
+//	  USER: app
+//	  PASSWORD: secret
+//
+// Файл читается при открытии и перезаписывается целиком после каждой записи или удаления.
+// В файл попадают только последние версии, история версий хранится в памяти до конца работы
+type FileStore struct {
+	*MemoryStore
+	path   string
+	format string
+	saveMu sync.Mutex // записи в файл идут по очереди и в порядке изменений
+}
+
+// OpenFileStore открывает файл секретов. Формат определяется по расширению: .json, .yaml или .yml.
+// Отсутствующий файл создается при первой записи
+func OpenFileStore(path string) (*FileStore, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+	if format != "json" && format != "yaml" {
+		return nil, fmt.Errorf("неизвестный формат файла секретов %s, ожидается .json, .yaml или .yml", path)
+	}
+
+	secrets := map[string]map[string]interface{}{}
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		err = nil
+	case err != nil:
+		return nil, fmt.Errorf("не удалось прочитать файл секретов %s: %w", path, err)
+	case format == "json":
+		err = json.Unmarshal(content, &secrets)
+	default:
+		err = yaml.Unmarshal(content, &secrets)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при разборе файла секретов %s: %w", path, err)
+	}
+	return &FileStore{MemoryStore: NewMemoryStore(secrets), path: path, format: format}, nil
+}
+
+// Write записывает секрет и сохраняет файл
+func (s *FileStore) Write(ctx context.Context, path string, data map[string]interface{}) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if err := s.MemoryStore.Write(ctx, path, data); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Delete удаляет секрет и сохраняет файл
+func (s *FileStore) Delete(ctx context.Context, path string) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if err := s.MemoryStore.Delete(ctx, path); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// save записывает секреты во временный файл и переименовывает его, чтобы при сбое не остался обрезанный файл
+func (s *FileStore) save() error {
+	var content []byte
+	var err error
+	if s.format == "json" {
+		content, err = json.MarshalIndent(s.snapshot(), "", "  ")
+		content = append(content, '\n')
+	} else {
+		content, err = yaml.Marshal(s.snapshot())
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при сериализации секретов: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("не удалось создать временный файл для %s: %w", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("не удалось записать файл секретов %s: %w", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("не удалось записать файл секретов %s: %w", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("не удалось записать файл секретов %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// String возвращает описание хранилища для логов и отчетов
+func (s *FileStore) String() string {
+	return "file " + s.path
+}
@@ -0,0 +1,91 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"fmt"
+)
+
+// replaySecret копирует секрет KV v2 со всей историей: версии записываются по порядку, начиная с 1.
+// Удаленные и уничтоженные версии, а также версии до oldest_version недоступны для чтения, поэтому
+// на их место пишется пустая версия, которая затем удаляется или уничтожается - номера и состояния версий сохраняются.
+// До версий копируются max_versions, cas_required и delete_version_after, чтобы max_versions engine копии
+// не удалил старые версии во время записи. После версий копируется custom_metadata.
+// Существующая история секрета в to удаляется
+func replaySecret(ctx context.Context, from *Client, fromPath string, to *Client, toPath string) error {
+	metadata, err := from.Metadata(ctx, fromPath)
+	if err != nil {
+		return err
+	}
+	if _, err := to.Metadata(ctx, toPath); err == nil {
+		if _, err := to.vault.Logical().DeleteWithContext(ctx, to.kvPath(ctx, toPath, "metadata")); err != nil {
+			return fmt.Errorf("не удалось удалить историю секрета %s: %w", toPath, err)
+		}
+	}
+
+	settings := map[string]interface{}{
+		"max_versions": metadata.MaxVersions,
+		"cas_required": metadata.CasRequired,
+	}
+	if metadata.DeleteVersionAfter != "" {
+		settings["delete_version_after"] = metadata.DeleteVersionAfter
+	}
+	if _, err := to.vault.Logical().WriteWithContext(ctx, to.kvPath(ctx, toPath, "metadata"), settings); err != nil {
+		return fmt.Errorf("не удалось записать метаданные %s: %w", toPath, err)
+	}
+
+	versions := map[int]Version{}
+	latest := 0
+	for _, version := range metadata.Versions {
+		versions[version.Version] = version
+		if version.Version > latest {
+			latest = version.Version
+		}
+	}
+	written := 0
+	for number := 1; number <= latest; number++ {
+		version, found := versions[number]
+		data := map[string]interface{}{}
+		if found && !version.Destroyed && !version.Deleted() {
+			if data, err = from.ReadVersion(ctx, fromPath, number); err != nil {
+				return err
+			}
+		}
+		// cas защищает от записи поверх чужого изменения и нужен, если в engine включен cas_required
+		_, err := to.vault.Logical().WriteWithContext(ctx, to.kvPath(ctx, toPath, "data"), map[string]interface{}{
+			"options": map[string]interface{}{"cas": written},
+			"data":    data,
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка при записи версии %d секрета %s: %w", number, toPath, err)
+		}
+		written++
+
+		action := ""
+		switch {
+		case !found || version.Destroyed:
+			action = "destroy"
+		case version.Deleted():
+			action = "delete"
+		}
+		if action != "" {
+			_, err := to.vault.Logical().WriteWithContext(ctx, to.kvPath(ctx, toPath, action), map[string]interface{}{"versions": []int{written}})
+			if err != nil {
+				return fmt.Errorf("ошибка при переносе состояния версии %d секрета %s: %w", number, toPath, err)
+			}
+		}
+	}
+	to.logf(LogDebug, "Секрет %s скопирован с историей: %d версий", NormalizePath(toPath), written)
+
+	custom := metadata.CustomMetadata
+	if custom == nil {
+		custom = map[string]interface{}{}
+	}
+	return to.writeCustomMetadata(ctx, toPath, custom)
+}
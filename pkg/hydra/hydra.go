@@ -0,0 +1,125 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+// Package hydra - библиотека, на которой построена утилита hydra: клиент Vault с авторизацией,
+// политикой повторов и ограничением параллельных запросов, чтение и запись секретов KV v1 и v2,
+// история версий, резервное копирование и сверка копии.
+//
+// Все параметры передаются явно через Options и структуры параметров операций, запросы к Vault
+// принимают context.Context. Функции библиотеки не завершают процесс, а возвращают ошибки
+package hydra
+
+import (
+	"context"
+	"fmt"
+	vault "github.com/hashicorp/vault/api"
+	"sync"
+)
+
+// DefaultConcurrency - число параллельных запросов к Vault, если Options.Concurrency не задан
+const DefaultConcurrency = 4
+
+// Options - параметры клиента
+type Options struct {
+	Address     string           // Адрес Vault
+	Namespace   string           // Namespace Vault Enterprise (заголовок X-Vault-Namespace), пустой - root namespace
+	TLS         *vault.TLSConfig // Настройки TLS, nil - системные корневые сертификаты
+	Auth        AuthOptions      // Способ авторизации, см. Client.Login
+	Retry       RetryPolicy      // Политика повторов, нулевая - DefaultRetryPolicy
+	Concurrency int              // Число параллельных запросов к Vault, 0 - DefaultConcurrency
+	RateLimit   float64          // Ограничение запросов в секунду, 0 - без ограничения
+	Log         LogFunc          // Журнал сообщений, nil - сообщения не выводятся
+}
+
+// withDefaults подставляет значения по умолчанию для незаданных параметров
+func (o Options) withDefaults() Options {
+	if o.Retry.Attempts == 0 {
+		o.Retry = DefaultRetryPolicy
+	}
+	if o.Concurrency < 1 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+// Client - клиент Vault. Безопасен для использования из нескольких горутин
+type Client struct {
+	vault    *vault.Client
+	opts     Options
+	authMu   sync.Mutex // защищает opts.Auth: развернутый secret_id сохраняется для повторной авторизации
+	mountsMu sync.Mutex // защищает mounts и mountsRead
+	mounts   []string   // точки монтирования из sys/mounts без / в конце
+	// mountsRead - список mounts уже запрашивался. Без прав на sys/mounts он остается пустым
+	mountsRead bool
+}
+
+// New создает клиент и авторизуется в Vault способом из opts.Auth
+func New(ctx context.Context, opts Options) (*Client, error) {
+	client, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.Login(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClient создает клиент без авторизации. Переменные окружения VAULT_TOKEN и VAULT_NAMESPACE
+// не используются: токен и namespace берутся только из opts
+func NewClient(opts Options) (*Client, error) {
+	if opts.Address == "" {
+		return nil, fmt.Errorf("не задан адрес Vault")
+	}
+	opts = opts.withDefaults()
+	if err := opts.Retry.Validate(); err != nil {
+		return nil, err
+	}
+	config, err := opts.Retry.VaultConfig(opts.Address, opts.TLS, opts.Log)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при конфигурации TLS: %w", err)
+	}
+	client, err := vault.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при создании клиента Vault: %w", err)
+	}
+	client.ClearToken()
+	if opts.Namespace != "" {
+		client.SetNamespace(opts.Namespace)
+	} else {
+		client.ClearNamespace()
+	}
+	// Ограничение общее для всех параллельных запросов к этому Vault
+	if opts.RateLimit > 0 {
+		client.SetLimiter(opts.RateLimit, opts.Concurrency)
+	}
+	return &Client{vault: client, opts: opts}, nil
+}
+
+// Wrap возвращает клиент библиотеки для уже настроенного и авторизованного клиента Vault.
+// Из opts используются Concurrency и Log
+func Wrap(client *vault.Client, opts Options) *Client {
+	return &Client{vault: client, opts: opts.withDefaults()}
+}
+
+// Vault возвращает клиент Vault, через который выполняются запросы
+func (c *Client) Vault() *vault.Client {
+	return c.vault
+}
+
+// String возвращает адрес Vault вместе с namespace для логов
+func (c *Client) String() string {
+	return DescribeVault(c.vault.Address(), c.vault.Namespace())
+}
+
+// DescribeVault возвращает адрес Vault вместе с namespace для логов
+func DescribeVault(address, namespace string) string {
+	if namespace == "" {
+		return address
+	}
+	return fmt.Sprintf("%s (namespace: %s)", address, namespace)
+}
@@ -0,0 +1,293 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"fmt"
+	vault "github.com/hashicorp/vault/api"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Read читает секрет KV v1 или v2. Путь KV v2 можно указать как с data (myns/data/app/db), так и без:
+// если Vault ответит, что engine версионный, чтение повторяется по пути с data.
+// Для отсутствующего секрета возвращается ErrNotFound, для пустого - пустая карта
+func (c *Client) Read(ctx context.Context, path string) (map[string]interface{}, error) {
+	c.logf(LogDebug, "Пробую прочитать из %s", path)
+
+	// Читаем секрет по исходному пути
+	secret, err := c.vault.Logical().ReadWithContext(ctx, path)
+	if err != nil {
+		c.logf(LogDebug, "Ошибка при выполнении операции vault по пути: %s Ошибка: %s", path, err)
+		return nil, err
+	}
+	if secret == nil {
+		c.logf(LogDebug, "Секрет не найден по пути: %s", path)
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+
+	// Если путь без data, проверяем, не ответил ли Vault, что engine версионный
+	if mount := c.mountOf(ctx, path); !checkPath(path, mount) {
+		modifiedPath := c.handleEngineV2(secret, path, mount, "Read", false)
+		if modifiedPath != path {
+			secret, err = c.vault.Logical().ReadWithContext(ctx, modifiedPath)
+			if err != nil {
+				c.logf(LogDebug, "Ошибка при выполнении операции vault по модифицированному пути: %s Ошибка: %s", modifiedPath, err)
+				return nil, err
+			}
+			if secret == nil {
+				c.logf(LogDebug, "Секрет не найден по модифицированному пути: %s", modifiedPath)
+				return nil, fmt.Errorf("%s: %w", modifiedPath, ErrNotFound)
+			}
+			path = modifiedPath
+		}
+	}
+
+	// Извлекаем данные из секрета
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		// Для KV v1 и других случаев, когда данные находятся на верхнем уровне
+		data = secret.Data
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	c.logf(LogDebug, "Успешное чтение из %s", path)
+	return data, nil
+}
+
+// Write записывает секрет KV v1 или v2, путь определяется так же, как в Read
+func (c *Client) Write(ctx context.Context, path string, data map[string]interface{}) error {
+	c.logf(LogDebug, "Пробую записать в %s", path)
+
+	// Для KV v2 данные оборачиваются в data
+	mount := c.mountOf(ctx, path)
+	wrappedData := data
+	if checkPath(path, mount) {
+		wrappedData = map[string]interface{}{"data": data}
+	}
+
+	result, err := c.vault.Logical().WriteWithContext(ctx, path, wrappedData)
+	if err != nil {
+		if modifiedPath := c.handleEngineV2(result, path, mount, "Write", false); modifiedPath != path {
+			// Повторяем попытку с модифицированным путем
+			return c.Write(ctx, modifiedPath, data)
+		}
+		return err
+	}
+	c.logf(LogDebug, "Успешная запись в %s", path)
+	return nil
+}
+
+// Walk рекурсивно обходит путь и возвращает пути всех секретов в нем в порядке ключей Vault.
+// Пути секретов KV v2 возвращаются с data: myns/data/app/db. Папки обходятся параллельно,
+// одновременно выполняется не больше Options.Concurrency запросов. Для отсутствующего
+// или пустого пути возвращается пустой список
+func (c *Client) Walk(ctx context.Context, root string) ([]string, error) {
+	var paths []string
+	slots := make(chan struct{}, c.opts.Concurrency)
+	err := c.walkPath(ctx, root+"/", &paths, false, slots)
+	return paths, err
+}
+
+// walkPath добавляет в secretsList секреты по пути currentPath. Вложенные папки обходятся параллельно,
+// каждый запрос к Vault занимает место в slots. Список собирается в порядке ключей Vault,
+// как при последовательном обходе
+func (c *Client) walkPath(ctx context.Context, currentPath string, secretsList *[]string, isModified bool, slots chan struct{}) error {
+	c.logf(LogDebug, "Пробуем путь: %s", currentPath)
+	mount := c.mountOf(ctx, currentPath)
+
+	// Попробуем считать секрет через Read
+	slots <- struct{}{}
+	secret, err := c.vault.Logical().ReadWithContext(ctx, currentPath)
+	<-slots
+	if err != nil {
+		c.logf(LogDebug, "Ошибка при чтении пути: %s Error: %s", currentPath, err)
+	}
+	if secret != nil && len(secret.Data) > 0 {
+		finalPath := modifyPathForDisplay(currentPath, mount)
+		*secretsList = append(*secretsList, finalPath)
+		c.logf(LogDebug, "Секрет найден и добавлен в лист: %s", finalPath)
+		return nil
+	}
+
+	// Если Read вернул nil, пробуем List
+	slots <- struct{}{}
+	secret, err = c.vault.Logical().ListWithContext(ctx, currentPath)
+	<-slots
+	if err != nil {
+		c.logf(LogDebug, "Ошибка при выполнении операции List: %s Error: %s", currentPath, err)
+		return err
+	}
+	if secret == nil {
+		c.logf(LogError, "Путь не существует либо пуст: %s", currentPath)
+		return nil
+	}
+
+	// Проверка на движок V2 (если путь еще не модифицирован)
+	if modifiedPath := c.handleEngineV2(secret, currentPath, mount, "List", isModified); modifiedPath != currentPath {
+		return c.walkPath(ctx, modifiedPath, secretsList, true, slots)
+	}
+
+	// Обработка ключей внутри папки
+	keysInterface, ok := secret.Data["keys"]
+	if !ok {
+		c.logf(LogDebug, "Нет ключей по пути: %s", currentPath)
+		return nil
+	}
+	keys, ok := keysInterface.([]interface{})
+	if !ok {
+		return fmt.Errorf("Невалидный ключ по пути: %s", currentPath)
+	}
+
+	// Для каждого ключа свой список, чтобы порядок не зависел от того, какая папка обошлась раньше
+	found := make([][]string, len(keys))
+	var wg sync.WaitGroup
+	for i, keyInterface := range keys {
+		key, ok := keyInterface.(string)
+		if !ok {
+			continue
+		}
+
+		fullPath := currentPath + key
+		if strings.HasSuffix(key, "/") {
+			// Рекурсивный обход для папки
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := c.walkPath(ctx, fullPath, &found[i], false, slots); err != nil {
+					c.logf(LogDebug, "Ошибка при переборе пути: %s Error: %s", fullPath, err)
+				}
+			}(i)
+		} else if key == backupStateName {
+			// Состояние инкрементальной копии - служебный секрет, а не секрет пользователя
+			continue
+		} else {
+			// Обработка секретов
+			finalPath := modifyPathForDisplay(fullPath, mount)
+			found[i] = []string{finalPath}
+			c.logf(LogDebug, "Секрет добавлен в лист: %s", finalPath)
+		}
+	}
+	wg.Wait()
+	for _, paths := range found {
+		*secretsList = append(*secretsList, paths...)
+	}
+	return nil
+}
+
+// Delete удаляет секрет. У секрета KV v2 удаляются все версии и метаданные
+func (c *Client) Delete(ctx context.Context, path string) error {
+	if _, err := c.Metadata(ctx, path); err == nil {
+		_, err = c.vault.Logical().DeleteWithContext(ctx, c.kvPath(ctx, path, "metadata"))
+		return err
+	}
+	_, err := c.vault.Logical().DeleteWithContext(ctx, path)
+	return err
+}
+
+// handleEngineV2 проверяет - если путь V1 и мы получили ошибку о неправильном engine то модифицируем его и возвращаем как V2 иначе просто вернет тот же путь.
+// mount - точка монтирования пути, после нее добавляется data или metadata
+func (c *Client) handleEngineV2(secret *vault.Secret, path, mount string, operation string, isModified bool) string {
+	if secret == nil || isModified || checkPath(path, mount) {
+		return path
+	}
+	if len(secret.Warnings) > 0 && strings.Contains(secret.Warnings[0], "Invalid path for a versioned K/V secrets engine") {
+		modifiedPath := modifyPathForV2(path, mount, operation)
+		c.logf(LogDebug, "Пробуем V2 engine %s", modifiedPath)
+		return modifiedPath
+	}
+	return path
+}
+
+// splitMount делит путь на точку монтирования и остаток без начального /. Если mount пустой
+// или путь лежит не в нем, точкой монтирования считается первый сегмент пути
+func splitMount(path, mount string) (string, string) {
+	path = strings.TrimPrefix(path, "/")
+	mount = strings.Trim(mount, "/")
+	if mount != "" && (path == mount || strings.HasPrefix(path, mount+"/")) {
+		return mount, strings.TrimPrefix(path[len(mount):], "/")
+	}
+	name, rest, _ := strings.Cut(path, "/")
+	return name, rest
+}
+
+// checkPath проверяет, что сегмент сразу после точки монтирования mount - "data" или "metadata".
+// Возвращает true, если это так, и false, если нет. Сравнивается сегмент целиком,
+// чтобы секреты вроде myns/database не принимались за путь KV v2
+func checkPath(path, mount string) bool {
+	// Отделяем точку монтирования, следующий сегмент и остаток.
+	_, rest := splitMount(path, mount)
+	segment, _, _ := strings.Cut(rest, "/")
+	return segment == "data" || segment == "metadata"
+}
+
+// modifyPathForV2 добавляет после точки монтирования mount data для чтения и записи или metadata для списка
+func modifyPathForV2(path, mount, operation string) string {
+	// Удаляем начальный слеш, если он есть
+	trimmedPath := strings.TrimPrefix(path, "/")
+	name, rest := splitMount(trimmedPath, mount)
+	segment, _, _ := strings.Cut(rest, "/")
+
+	switch {
+	// Для операций чтения и записи добавляем "data" после монтирования точки.
+	case (operation == "Read" || operation == "Write") && rest != "":
+		return name + "/data/" + rest
+	// Для операции List добавляем "metadata" после монтирования точки, если его еще нет
+	case operation == "List" && segment == "metadata":
+		return trimmedPath
+	case operation == "List" && trimmedPath == name:
+		// Если в пути только монтирование точки, добавляем "metadata"
+		return name + "/metadata"
+	case operation == "List":
+		return name + "/metadata/" + rest
+	}
+	return trimmedPath
+}
+
+// modifyPathForDisplay заменяет metadata после точки монтирования mount на data для KV V2
+func modifyPathForDisplay(path, mount string) string {
+	name, rest := splitMount(path, mount)
+	if after, ok := strings.CutPrefix(rest, "metadata/"); ok {
+		return name + "/data/" + after
+	}
+	return path
+}
+
+// KVv2Path возвращает путь KV v2 с нужным сегментом (data, metadata, delete, destroy) после точки монтирования mount.
+// Путь можно указать как с data/metadata, так и без. Пустой mount - точка монтирования в первом сегменте пути
+func KVv2Path(path, mount, segment string) string {
+	name, rest := splitMount(strings.Trim(path, "/"), mount)
+	if first, after, _ := strings.Cut(rest, "/"); first == "data" || first == "metadata" {
+		rest = after
+	}
+	if rest == "" {
+		return name + "/" + segment
+	}
+	return name + "/" + segment + "/" + rest
+}
+
+// kvPath возвращает путь KV v2 с сегментом segment, точка монтирования определяется по списку engine Vault
+func (c *Client) kvPath(ctx context.Context, path, segment string) string {
+	return KVv2Path(path, c.mountOf(ctx, path), segment)
+}
+
+// NormalizePath приводит путь к виду без /data/ KV v2 и крайних /: myns/data/app/db -> myns/app/db
+func NormalizePath(path string) string {
+	return strings.Trim(strings.Replace(path, "/data/", "/", 1), "/")
+}
+
+// SameData сравнивает данные секретов, пустой секрет равен отсутствующим данным
+func SameData(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
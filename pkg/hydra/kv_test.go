@@ -0,0 +1,298 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"errors"
+	vault "github.com/hashicorp/vault/api"
+	"hydra/pkg/hydra/vaulttest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRetry - политика без повторов, чтобы ошибки в тестах не ждали пауз
+var testRetry = RetryPolicy{Attempts: 1, WaitMin: time.Millisecond, WaitMax: time.Millisecond, Timeout: 5 * time.Second}
+
+// newTestClient возвращает клиент с корневым токеном поддельного Vault
+func newTestClient(t *testing.T, server *vaulttest.Server) *Client {
+	t.Helper()
+	client, err := New(context.Background(), Options{
+		Address: server.URL,
+		Auth:    AuthOptions{Token: server.RootToken()},
+		Retry:   testRetry,
+	})
+	if err != nil {
+		t.Fatalf("не удалось подключиться к поддельному Vault: %v", err)
+	}
+	return client
+}
+
+// newTestServer возвращает поддельный Vault с KV v2 secret и KV v1 legacy
+func newTestServer(t *testing.T) *vaulttest.Server {
+	t.Helper()
+	server := vaulttest.NewServer(t)
+	server.Mount("legacy", 1)
+	server.Put("secret/app/db", map[string]interface{}{"user": "app", "password": "p1"})
+	server.Put("secret/app/api", map[string]interface{}{"token": "t1"})
+	server.Put("secret/database", map[string]interface{}{"dsn": "postgres://db"})
+	server.Put("secret/team/x/y", map[string]interface{}{"z": "1"})
+	server.Put("legacy/app/x", map[string]interface{}{"a": "1"})
+	server.Put("legacy/database", map[string]interface{}{"b": "2"})
+	return server
+}
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		mount string
+		want  bool
+	}{
+		{"secret/data/app/db", "", true},
+		{"secret/metadata/app/", "", true},
+		{"secret/metadata/", "", true},
+		{"secret/data", "", true},
+		{"/secret/data/app/db", "", true},
+		{"secret/app/db", "", false},
+		{"secret/app/data", "", false},
+		{"secret/database", "", false},
+		{"secret/metadata-archive/app", "", false},
+		{"secret/", "", false},
+		{"secret", "", false},
+		{"", "", false},
+		{"secret/data/app/db", "secret", true},
+		{"team/kv/data/app/db", "team/kv", true},
+		{"team/kv/metadata/", "team/kv", true},
+		{"team/kv/app/db", "team/kv", false},
+		{"team/data/app", "team/kv", true},
+	}
+	for _, tt := range tests {
+		if got := checkPath(tt.path, tt.mount); got != tt.want {
+			t.Errorf("checkPath(%q, %q) = %v, ожидается %v", tt.path, tt.mount, got, tt.want)
+		}
+	}
+}
+
+func TestModifyPathForV2(t *testing.T) {
+	tests := []struct {
+		path      string
+		mount     string
+		operation string
+		want      string
+	}{
+		{"secret/app/db", "", "Read", "secret/data/app/db"},
+		{"secret/app/db", "", "Write", "secret/data/app/db"},
+		{"/secret/app/db", "", "Read", "secret/data/app/db"},
+		{"secret", "", "Read", "secret"},
+		{"secret/", "", "List", "secret/metadata/"},
+		{"secret", "", "List", "secret/metadata"},
+		{"secret/app/", "", "List", "secret/metadata/app/"},
+		{"secret/metadata/app/", "", "List", "secret/metadata/app/"},
+		{"secret/app/db", "", "Delete", "secret/app/db"},
+		{"team/kv/app/db", "team/kv", "Read", "team/kv/data/app/db"},
+		{"team/kv/", "team/kv", "List", "team/kv/metadata/"},
+		{"team/kv", "team/kv", "List", "team/kv/metadata"},
+		{"team/kv/metadata/app/", "team/kv", "List", "team/kv/metadata/app/"},
+		{"team/kv", "team/kv", "Read", "team/kv"},
+	}
+	for _, tt := range tests {
+		if got := modifyPathForV2(tt.path, tt.mount, tt.operation); got != tt.want {
+			t.Errorf("modifyPathForV2(%q, %q, %q) = %q, ожидается %q", tt.path, tt.mount, tt.operation, got, tt.want)
+		}
+	}
+}
+
+func TestKVv2Path(t *testing.T) {
+	tests := []struct {
+		path    string
+		mount   string
+		segment string
+		want    string
+	}{
+		{"secret/app/db", "", "metadata", "secret/metadata/app/db"},
+		{"secret/data/app/db", "", "metadata", "secret/metadata/app/db"},
+		{"/secret/app/db/", "secret", "data", "secret/data/app/db"},
+		{"secret", "", "metadata", "secret/metadata"},
+		{"team/kv/app/db", "team/kv", "data", "team/kv/data/app/db"},
+		{"team/kv/data/app/db", "team/kv", "destroy", "team/kv/destroy/app/db"},
+		{"team/kv/app/db", "", "data", "team/data/kv/app/db"},
+	}
+	for _, tt := range tests {
+		if got := KVv2Path(tt.path, tt.mount, tt.segment); got != tt.want {
+			t.Errorf("KVv2Path(%q, %q, %q) = %q, ожидается %q", tt.path, tt.mount, tt.segment, got, tt.want)
+		}
+	}
+}
+
+func TestHandleEngineV2(t *testing.T) {
+	invalidPath := &vault.Secret{Warnings: []string{"Invalid path for a versioned K/V secrets engine. See the API docs for the appropriate API endpoints to use."}}
+	tests := []struct {
+		name       string
+		secret     *vault.Secret
+		path       string
+		mount      string
+		operation  string
+		isModified bool
+		want       string
+	}{
+		{"нет ответа", nil, "secret/app/db", "", "Read", false, "secret/app/db"},
+		{"чтение KV v2", invalidPath, "secret/app/db", "", "Read", false, "secret/data/app/db"},
+		{"запись KV v2", invalidPath, "secret/app/db", "", "Write", false, "secret/data/app/db"},
+		{"список KV v2", invalidPath, "secret/app/", "", "List", false, "secret/metadata/app/"},
+		{"секрет database", invalidPath, "secret/database", "", "Read", false, "secret/data/database"},
+		{"путь уже изменен", invalidPath, "secret/app/", "", "List", true, "secret/app/"},
+		{"путь уже с data", invalidPath, "secret/data/app/db", "", "Read", false, "secret/data/app/db"},
+		{"другое предупреждение", &vault.Secret{Warnings: []string{"Endpoint ignored these unrecognized parameters: [x]"}}, "secret/app/db", "", "Read", false, "secret/app/db"},
+		{"секрет KV v1", &vault.Secret{Data: map[string]interface{}{"a": "1"}}, "legacy/app/x", "", "Read", false, "legacy/app/x"},
+		{"вложенный engine", invalidPath, "team/kv/app/db", "team/kv", "Read", false, "team/kv/data/app/db"},
+	}
+	client := &Client{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.handleEngineV2(tt.secret, tt.path, tt.mount, tt.operation, tt.isModified); got != tt.want {
+				t.Errorf("handleEngineV2(%q, %q) = %q, ожидается %q", tt.path, tt.operation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRead(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	tests := []struct {
+		name     string
+		path     string
+		want     map[string]interface{}
+		wantErr  error
+		requests []string // запросы к Vault при чтении
+	}{
+		{
+			name: "KV v2 без data",
+			path: "secret/app/db",
+			want: map[string]interface{}{"user": "app", "password": "p1"},
+			// Список engine запрашивается один раз при первом обращении клиента
+			requests: []string{"GET secret/app/db", "GET sys/mounts", "GET secret/data/app/db"},
+		},
+		{
+			name:     "KV v2 с data",
+			path:     "secret/data/app/db",
+			want:     map[string]interface{}{"user": "app", "password": "p1"},
+			requests: []string{"GET secret/data/app/db"},
+		},
+		{
+			name:     "KV v2 секрет с именем на data",
+			path:     "secret/database",
+			want:     map[string]interface{}{"dsn": "postgres://db"},
+			requests: []string{"GET secret/database", "GET secret/data/database"},
+		},
+		{
+			name:     "KV v1",
+			path:     "legacy/app/x",
+			want:     map[string]interface{}{"a": "1"},
+			requests: []string{"GET legacy/app/x"},
+		},
+		{
+			name:     "KV v1 секрет с именем на data",
+			path:     "legacy/database",
+			want:     map[string]interface{}{"b": "2"},
+			requests: []string{"GET legacy/database"},
+		},
+		{
+			name:     "KV v2 отсутствует",
+			path:     "secret/app/none",
+			wantErr:  ErrNotFound,
+			requests: []string{"GET secret/app/none", "GET secret/data/app/none"},
+		},
+		{
+			name:     "KV v1 отсутствует",
+			path:     "legacy/none",
+			wantErr:  ErrNotFound,
+			requests: []string{"GET legacy/none"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.ResetRequests()
+			got, err := client.Read(context.Background(), tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Read(%q): ошибка %v, ожидается %v", tt.path, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read(%q) = %v, ожидается %v", tt.path, got, tt.want)
+			}
+			if requests := server.Requests(); !reflect.DeepEqual(requests, tt.requests) {
+				t.Errorf("запросы к Vault: %v, ожидается %v", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestClientWrite(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	tests := []struct {
+		name     string
+		path     string
+		stored   string // путь, по которому секрет должен оказаться в Vault
+		versions int    // число версий KV v2 после записи
+	}{
+		{"новый секрет KV v2 без data", "secret/app/new", "secret/app/new", 1},
+		{"новая версия KV v2 с data", "secret/data/app/db", "secret/app/db", 2},
+		{"KV v2 секрет с именем на data", "secret/database", "secret/database", 2},
+		{"KV v1", "legacy/app/y", "legacy/app/y", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{"key": tt.name}
+			if err := client.Write(context.Background(), tt.path, data); err != nil {
+				t.Fatalf("Write(%q): %v", tt.path, err)
+			}
+			if got := server.Get(tt.stored); !reflect.DeepEqual(got, data) {
+				t.Errorf("в Vault по пути %s: %v, ожидается %v", tt.stored, got, data)
+			}
+			if got := server.Versions(tt.stored); got != tt.versions {
+				t.Errorf("версий %s: %d, ожидается %d", tt.stored, got, tt.versions)
+			}
+		})
+	}
+}
+
+func TestClientWalk(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	tests := []struct {
+		root string
+		want []string
+	}{
+		{"secret", []string{"secret/data/app/api", "secret/data/app/db", "secret/data/database", "secret/data/team/x/y"}},
+		{"secret/app", []string{"secret/data/app/api", "secret/data/app/db"}},
+		{"legacy", []string{"legacy/app/x", "legacy/database"}},
+		{"secret/none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			got, err := client.Walk(context.Background(), tt.root)
+			if err != nil {
+				t.Fatalf("Walk(%q): %v", tt.root, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Walk(%q) = %v, ожидается %v", tt.root, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRequiresToken(t *testing.T) {
+	server := newTestServer(t)
+	_, err := New(context.Background(), Options{Address: server.URL, Auth: AuthOptions{Token: "hvs.unknown"}, Retry: testRetry})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("ожидается отказ в доступе для неизвестного токена, получено: %v", err)
+	}
+}
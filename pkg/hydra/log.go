@@ -0,0 +1,38 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import "fmt"
+
+// LogLevel - уровень сообщения, совпадает с уровнями VAULT_VERBOSE утилиты hydra
+type LogLevel int
+
+const (
+	LogError LogLevel = 1
+	LogInfo  LogLevel = 2
+	LogDebug LogLevel = 3
+)
+
+// LogFunc принимает сообщения библиотеки. Вызывается из нескольких горутин
+type LogFunc func(level LogLevel, message string)
+
+// logf форматирует сообщение и передает его в log, если он задан
+func (log LogFunc) logf(level LogLevel, format string, args ...interface{}) {
+	if log == nil {
+		return
+	}
+	if len(args) > 0 {
+		format = fmt.Sprintf(format, args...)
+	}
+	log(level, format)
+}
+
+// logf пишет сообщение в журнал клиента
+func (c *Client) logf(level LogLevel, format string, args ...interface{}) {
+	c.opts.Log.logf(level, format, args...)
+}
@@ -0,0 +1,206 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Version - одна версия секрета из метаданных KV v2
+type Version struct {
+	Version      int    `json:"version"`
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time,omitempty"`
+	Destroyed    bool   `json:"destroyed,omitempty"`
+}
+
+// Metadata - метаданные секрета KV v2. В таком виде они также сохраняются в архив hydra export
+type Metadata struct {
+	CurrentVersion     int                    `json:"current_version"`
+	OldestVersion      int                    `json:"oldest_version"`
+	MaxVersions        int                    `json:"max_versions"`
+	CasRequired        bool                   `json:"cas_required"`
+	DeleteVersionAfter string                 `json:"delete_version_after,omitempty"`
+	CreatedTime        string                 `json:"created_time"`
+	UpdatedTime        string                 `json:"updated_time"`
+	CustomMetadata     map[string]interface{} `json:"custom_metadata,omitempty"`
+	Versions           []Version              `json:"versions"`
+}
+
+// Deleted сообщает, удалена ли версия. При delete_version_after Vault заранее
+// проставляет deletion_time в будущем, такая версия пока доступна
+func (v Version) Deleted() bool {
+	if v.DeletionTime == "" {
+		return false
+	}
+	deletion, err := time.Parse(time.RFC3339Nano, v.DeletionTime)
+	return err != nil || !deletion.After(time.Now())
+}
+
+// State возвращает состояние версии: active, deleted или destroyed
+func (v Version) State() string {
+	switch {
+	case v.Destroyed:
+		return "destroyed"
+	case v.Deleted():
+		return "deleted"
+	}
+	return "active"
+}
+
+// Metadata читает метаданные секрета KV v2 со списком версий.
+// Для секрета KV v1 или отсутствующего секрета возвращается ErrNotKVv2
+func (c *Client) Metadata(ctx context.Context, path string) (*Metadata, error) {
+	metadataPath := c.kvPath(ctx, path, "metadata")
+	secret, err := c.vault.Logical().ReadWithContext(ctx, metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении метаданных %s: %w", metadataPath, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("метаданные %s не найдены: %w", metadataPath, ErrNotKVv2)
+	}
+
+	metadata := &Metadata{
+		CurrentVersion:     intValue(secret.Data["current_version"]),
+		OldestVersion:      intValue(secret.Data["oldest_version"]),
+		MaxVersions:        intValue(secret.Data["max_versions"]),
+		DeleteVersionAfter: stringValue(secret.Data["delete_version_after"]),
+		CreatedTime:        stringValue(secret.Data["created_time"]),
+		UpdatedTime:        stringValue(secret.Data["updated_time"]),
+	}
+	metadata.CasRequired, _ = secret.Data["cas_required"].(bool)
+	metadata.CustomMetadata, _ = secret.Data["custom_metadata"].(map[string]interface{})
+	versions, _ := secret.Data["versions"].(map[string]interface{})
+	for number, info := range versions {
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			continue
+		}
+		fields, _ := info.(map[string]interface{})
+		destroyed, _ := fields["destroyed"].(bool)
+		metadata.Versions = append(metadata.Versions, Version{
+			Version:      version,
+			CreatedTime:  stringValue(fields["created_time"]),
+			DeletionTime: stringValue(fields["deletion_time"]),
+			Destroyed:    destroyed,
+		})
+	}
+	sort.Slice(metadata.Versions, func(i, j int) bool { return metadata.Versions[i].Version < metadata.Versions[j].Version })
+	return metadata, nil
+}
+
+// ReadVersion читает указанную версию секрета KV v2. Для удаленной или уничтоженной
+// версии возвращается ErrVersionUnavailable, для отсутствующей - ErrNotFound
+func (c *Client) ReadVersion(ctx context.Context, path string, version int) (map[string]interface{}, error) {
+	dataPath := c.kvPath(ctx, path, "data")
+	c.logf(LogDebug, "Пробую прочитать версию %d из %s", version, dataPath)
+	secret, err := c.vault.Logical().ReadWithDataWithContext(ctx, dataPath, map[string][]string{"version": {strconv.Itoa(version)}})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении версии %d секрета %s: %w", version, path, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("версия %d секрета %s не найдена: %w", version, path, ErrNotFound)
+	}
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		// Vault возвращает только метаданные, если версия удалена или уничтожена
+		return nil, fmt.Errorf("версия %d секрета %s: %w", version, path, ErrVersionUnavailable)
+	}
+	c.logf(LogDebug, "Успешное чтение версии %d из %s", version, path)
+	return data, nil
+}
+
+// Rollback записывает данные версии version как новую последнюю версию и возвращает ее номер.
+// Запись идет с check-and-set по текущей версии, чтобы не перезаписать изменение, сделанное во время отката.
+// Если version уже последняя, ничего не записывается и возвращается она же
+func (c *Client) Rollback(ctx context.Context, path string, version int) (int, error) {
+	metadata, err := c.Metadata(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	if version == metadata.CurrentVersion {
+		return version, nil
+	}
+	data, err := c.ReadVersion(ctx, path, version)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := c.vault.Logical().WriteWithContext(ctx, c.kvPath(ctx, path, "data"), map[string]interface{}{
+		"options": map[string]interface{}{"cas": metadata.CurrentVersion},
+		"data":    data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при записи версии %d секрета %s: %w", version, path, err)
+	}
+	newVersion := metadata.CurrentVersion + 1
+	if result != nil {
+		if n := intValue(result.Data["version"]); n > 0 {
+			newVersion = n
+		}
+	}
+	return newVersion, nil
+}
+
+// WriteMetadata записывает настройки секрета KV v2 из metadata: max_versions, cas_required,
+// delete_version_after и custom_metadata. Номера версий и их история не меняются
+func (c *Client) WriteMetadata(ctx context.Context, path string, metadata *Metadata) error {
+	custom := metadata.CustomMetadata
+	if custom == nil {
+		custom = map[string]interface{}{}
+	}
+	settings := map[string]interface{}{
+		"max_versions":    metadata.MaxVersions,
+		"cas_required":    metadata.CasRequired,
+		"custom_metadata": custom,
+	}
+	if metadata.DeleteVersionAfter != "" {
+		settings["delete_version_after"] = metadata.DeleteVersionAfter
+	}
+	if _, err := c.vault.Logical().WriteWithContext(ctx, c.kvPath(ctx, path, "metadata"), settings); err != nil {
+		return fmt.Errorf("не удалось записать метаданные %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeCustomMetadata записывает custom_metadata секрета KV v2
+func (c *Client) writeCustomMetadata(ctx context.Context, path string, custom map[string]interface{}) error {
+	_, err := c.vault.Logical().WriteWithContext(ctx, c.kvPath(ctx, path, "metadata"), map[string]interface{}{
+		"custom_metadata": custom,
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось записать метаданные %s: %w", path, err)
+	}
+	return nil
+}
+
+// intValue приводит число из ответа Vault (json.Number или float64) к int
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
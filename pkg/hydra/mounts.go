@@ -0,0 +1,450 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	vault "github.com/hashicorp/vault/api"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupMount - engine и пути в нем, которые копируются
+type BackupMount struct {
+	Name  string
+	Roots []string
+}
+
+// kvMountTypes - типы engine, которые копируются при пути *
+var kvMountTypes = map[string]bool{"kv": true, "kv-v2": true, "generic": true}
+
+// BackupMounts разбирает пути копирования: несколько путей или * - все engine KV.
+// Пути группируются по engine (самая длинная точка монтирования из sys/mounts) в порядке их указания
+func (c *Client) BackupMounts(ctx context.Context, paths []string) ([]BackupMount, error) {
+	if len(paths) == 1 && paths[0] == "*" {
+		mounts, err := c.vault.Sys().ListMountsWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить список монтирований: %w", err)
+		}
+		var names []string
+		for path, mount := range mounts {
+			if kvMountTypes[mount.Type] {
+				names = append(names, strings.TrimSuffix(path, "/"))
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("в %s нет engine KV", c)
+		}
+		sort.Strings(names)
+		result := make([]BackupMount, 0, len(names))
+		for _, name := range names {
+			result = append(result, BackupMount{Name: name, Roots: []string{name}})
+		}
+		return result, nil
+	}
+	return groupMounts(paths, c.mountList(ctx))
+}
+
+// groupMounts группирует пути по engine в порядке их указания. Engine пути - самая длинная
+// точка монтирования из mounts, в которой он лежит, иначе первый сегмент пути
+func groupMounts(paths []string, mounts []string) ([]BackupMount, error) {
+	var result []BackupMount
+	index := map[string]int{}
+	for _, path := range paths {
+		if path == "*" {
+			return nil, fmt.Errorf("* нельзя сочетать с другими путями: %s", strings.Join(paths, " "))
+		}
+		root := strings.Trim(path, "/")
+		name, _ := splitMount(root, longestMount(mounts, root))
+		if name == "" {
+			return nil, fmt.Errorf("не удалось определить engine из пути: %s", path)
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(result)
+			index[name] = i
+			result = append(result, BackupMount{Name: name})
+		}
+		result[i].Roots = append(result[i].Roots, root)
+	}
+	for i := range result {
+		result[i].Roots = collapseRoots(result[i].Roots)
+	}
+	return result, nil
+}
+
+// storeMounts разбирает пути копирования для любого хранилища. В хранилищах без engine
+// путь * раскрывается в первые сегменты путей всех секретов
+func storeMounts(ctx context.Context, store SecretStore, paths []string) ([]BackupMount, error) {
+	if client, ok := store.(*Client); ok {
+		return client.BackupMounts(ctx, paths)
+	}
+	if len(paths) != 1 || paths[0] != "*" {
+		return groupMounts(paths, nil)
+	}
+	all, err := store.Walk(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var result []BackupMount
+	seen := map[string]bool{}
+	for _, path := range all {
+		name := strings.Split(path, "/")[0]
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, BackupMount{Name: name, Roots: []string{name}})
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("в %s нет секретов", describeStore(store))
+	}
+	return result, nil
+}
+
+// mountOf возвращает точку монтирования пути: самый длинный подходящий engine из sys/mounts.
+// Если список engine недоступен или путь не лежит ни в одном из них, возвращается пустая строка
+// и точкой монтирования считается первый сегмент пути
+func (c *Client) mountOf(ctx context.Context, path string) string {
+	return longestMount(c.mountList(ctx), path)
+}
+
+// mountList возвращает точки монтирования Vault. Список запрашивается один раз и сбрасывается
+// после создания и удаления engine. Без прав на sys/mounts возвращается пустой список
+func (c *Client) mountList(ctx context.Context) []string {
+	c.mountsMu.Lock()
+	defer c.mountsMu.Unlock()
+	if c.mountsRead {
+		return c.mounts
+	}
+	c.mountsRead = true
+	mounts, err := c.vault.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		c.logf(LogDebug, "Список engine недоступен, точкой монтирования считается первый сегмент пути: %v", err)
+		return nil
+	}
+	c.mounts = make([]string, 0, len(mounts))
+	for path := range mounts {
+		c.mounts = append(c.mounts, strings.TrimSuffix(path, "/"))
+	}
+	return c.mounts
+}
+
+// resetMounts сбрасывает список точек монтирования после изменения engine
+func (c *Client) resetMounts() {
+	c.mountsMu.Lock()
+	defer c.mountsMu.Unlock()
+	c.mounts, c.mountsRead = nil, false
+}
+
+// longestMount возвращает самую длинную точку монтирования из mounts, в которой лежит путь, или пустую строку
+func longestMount(mounts []string, path string) string {
+	path = strings.Trim(path, "/")
+	best := ""
+	for _, mount := range mounts {
+		if underRoot(path, mount) && len(mount) > len(best) {
+			best = mount
+		}
+	}
+	return best
+}
+
+// BackupRoots возвращает все пути копирования, * раскрывается в список engine KV
+func (c *Client) BackupRoots(ctx context.Context, paths []string) ([]string, error) {
+	return storeRoots(ctx, c, paths)
+}
+
+// storeRoots возвращает все пути копирования в хранилище, * раскрывается так же, как в storeMounts
+func storeRoots(ctx context.Context, store SecretStore, paths []string) ([]string, error) {
+	mounts, err := storeMounts(ctx, store, paths)
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for _, mount := range mounts {
+		roots = append(roots, mount.Roots...)
+	}
+	return roots, nil
+}
+
+// collapseRoots убирает повторы и пути, которые уже входят в другой путь из списка
+func collapseRoots(roots []string) []string {
+	var result []string
+	for i, root := range roots {
+		covered := false
+		for j, other := range roots {
+			if (root != other && underRoot(root, other)) || (root == other && j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, root)
+		}
+	}
+	return result
+}
+
+// underRoot сообщает, что секрет path находится внутри пути root
+func underRoot(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+"/")
+}
+
+// underAnyRoot сообщает, что секрет path находится внутри одного из путей
+func underAnyRoot(path string, roots []string) bool {
+	for _, root := range roots {
+		if underRoot(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkRoots рекурсивно получает пути секретов по всем путям из списка
+func walkRoots(ctx context.Context, store SecretStore, roots []string) ([]string, error) {
+	var paths []string
+	for _, root := range roots {
+		rootPaths, err := store.Walk(ctx, root)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при получении списка путей секретов %s: %w", root, err)
+		}
+		paths = append(paths, rootPaths...)
+	}
+	return paths, nil
+}
+
+// backupMountInput возвращает параметры engine копии. Тип и версия KV всегда берутся из engine
+// источника (KV v1 остается v1). С history также копируются описание и настройки,
+// иначе в описании ставится пометка о копии
+func (c *Client) backupMountInput(ctx context.Context, mount string, history bool) (*vault.MountInput, error) {
+	mounts, err := c.vault.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить список монтирований источника: %w", err)
+	}
+	source, ok := mounts[mount+"/"]
+	if !ok {
+		return nil, fmt.Errorf("engine '%s' не найден в источнике %s", mount, c)
+	}
+	if !history {
+		input := &vault.MountInput{
+			Type:        source.Type,
+			Description: fmt.Sprintf("[%s] Backup Engine from %s", time.Now().Format("02.01.2006 15:04:05"), c),
+		}
+		if version := source.Options["version"]; version != "" {
+			input.Options = map[string]string{"version": version}
+		}
+		return input, nil
+	}
+	return &vault.MountInput{
+		Type:                  source.Type,
+		Description:           source.Description,
+		Config:                mountConfigInput(source.Config),
+		Local:                 source.Local,
+		SealWrap:              source.SealWrap,
+		ExternalEntropyAccess: source.ExternalEntropyAccess,
+		Options:               source.Options,
+	}, nil
+}
+
+// mountConfigInput переводит настройки engine из ответа Vault в параметры монтирования
+func mountConfigInput(config vault.MountConfigOutput) vault.MountConfigInput {
+	input := vault.MountConfigInput{
+		ForceNoCache:              config.ForceNoCache,
+		AuditNonHMACRequestKeys:   config.AuditNonHMACRequestKeys,
+		AuditNonHMACResponseKeys:  config.AuditNonHMACResponseKeys,
+		ListingVisibility:         config.ListingVisibility,
+		PassthroughRequestHeaders: config.PassthroughRequestHeaders,
+		AllowedResponseHeaders:    config.AllowedResponseHeaders,
+		TokenType:                 config.TokenType,
+		AllowedManagedKeys:        config.AllowedManagedKeys,
+	}
+	if config.DefaultLeaseTTL > 0 {
+		input.DefaultLeaseTTL = fmt.Sprintf("%ds", config.DefaultLeaseTTL)
+	}
+	if config.MaxLeaseTTL > 0 {
+		input.MaxLeaseTTL = fmt.Sprintf("%ds", config.MaxLeaseTTL)
+	}
+	return input
+}
+
+// isKVv2Mount сообщает, что engine создается как KV v2
+func isKVv2Mount(input *vault.MountInput) bool {
+	return input.Type == "kv-v2" || (input.Type == "kv" && input.Options["version"] == "2")
+}
+
+// copyMountSettings переносит настройки уже существующего engine копии и настройки KV v2
+// (max_versions, cas_required, delete_version_after) из engine источника
+func copyMountSettings(ctx context.Context, src *Client, srcMount string, dst *Client, dstMount string, input *vault.MountInput, exists bool) error {
+	if exists {
+		config := input.Config
+		config.Description = &input.Description
+		if err := dst.vault.Sys().TuneMountWithContext(ctx, dstMount, config); err != nil {
+			return fmt.Errorf("не удалось обновить настройки engine '%s': %w", dstMount, err)
+		}
+	}
+	if !isKVv2Mount(input) {
+		return nil
+	}
+	config, err := src.vault.Logical().ReadWithContext(ctx, srcMount+"/config")
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать настройки engine '%s': %w", srcMount, err)
+	}
+	if config == nil || config.Data == nil {
+		return nil
+	}
+	_, err = dst.vault.Logical().WriteWithContext(ctx, dstMount+"/config", map[string]interface{}{
+		"max_versions":         config.Data["max_versions"],
+		"cas_required":         config.Data["cas_required"],
+		"delete_version_after": config.Data["delete_version_after"],
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось записать настройки engine '%s': %w", dstMount, err)
+	}
+	return nil
+}
+
+// hasMount сообщает, что engine смонтирован
+func (c *Client) hasMount(ctx context.Context, mount string) (bool, error) {
+	mounts, err := c.vault.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("не удалось получить список монтирований: %w", err)
+	}
+	_, ok := mounts[mount+"/"]
+	return ok, nil
+}
+
+// recreateMount удаляет engine, если он есть, и создает его заново с параметрами input
+func (c *Client) recreateMount(ctx context.Context, mount string, input *vault.MountInput) error {
+	exists, err := c.hasMount(ctx, mount)
+	if err != nil {
+		return err
+	}
+	if exists {
+		c.logf(LogInfo, "Engine '%s' уже существует, удаляем...", mount)
+		if err := c.vault.Sys().UnmountWithContext(ctx, mount+"/"); err != nil {
+			return fmt.Errorf("не удалось удалить engine '%s': %w", mount, err)
+		}
+		c.logf(LogInfo, "Engine '%s' успешно удалён", mount)
+	}
+
+	c.logf(LogDebug, "Создаем engine '%s' с типом %s", mount, input.Type)
+	if err := c.vault.Sys().MountWithContext(ctx, mount+"/", input); err != nil {
+		return fmt.Errorf("не удалось создать engine '%s': %w", mount, err)
+	}
+	c.resetMounts()
+	c.logf(LogInfo, "Engine '%s' успешно создан с типом %s в %s", mount, input.Type, c)
+	return nil
+}
+
+// ensureMount создает engine с параметрами input, если его нет. Существующий engine не трогается.
+// Возвращает true, если engine уже был
+func (c *Client) ensureMount(ctx context.Context, mount string, input *vault.MountInput) (bool, error) {
+	exists, err := c.hasMount(ctx, mount)
+	if err != nil || exists {
+		return exists, err
+	}
+	c.logf(LogInfo, "Engine '%s' не найден, создаем %s", mount, input.Type)
+	if err := c.vault.Sys().MountWithContext(ctx, mount+"/", input); err != nil {
+		return false, fmt.Errorf("не удалось создать engine '%s': %w", mount, err)
+	}
+	c.resetMounts()
+	return false, nil
+}
+
+// createStagingMount создает пустой staging engine, оставшийся от прошлого неудачного запуска удаляется
+func (c *Client) createStagingMount(ctx context.Context, staging string, input *vault.MountInput) error {
+	exists, err := c.hasMount(ctx, staging)
+	if err != nil {
+		return err
+	}
+	if exists {
+		c.logf(LogInfo, "Удаляем staging engine '%s' от прошлого запуска", staging)
+		if err := c.vault.Sys().UnmountWithContext(ctx, staging+"/"); err != nil {
+			return fmt.Errorf("не удалось удалить staging engine '%s': %w", staging, err)
+		}
+	}
+	if err := c.vault.Sys().MountWithContext(ctx, staging+"/", input); err != nil {
+		return fmt.Errorf("не удалось создать staging engine '%s': %w", staging, err)
+	}
+	c.resetMounts()
+	return nil
+}
+
+// swapStagingMount заменяет engine копии на staging: копия переименовывается, staging занимает ее место,
+// и только после этого старая копия удаляется. Если переименование staging не удалось, копия возвращается на место
+func (c *Client) swapStagingMount(ctx context.Context, mount, staging string) error {
+	defer c.resetMounts()
+	retired := mount + retiredMountSuffix
+	exists, err := c.hasMount(ctx, retired)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := c.vault.Sys().UnmountWithContext(ctx, retired+"/"); err != nil {
+			return fmt.Errorf("не удалось удалить engine '%s' от прошлого запуска: %w", retired, err)
+		}
+	}
+	if err := c.vault.Sys().RemountWithContext(ctx, mount, retired); err != nil {
+		return fmt.Errorf("не удалось переименовать engine '%s' в '%s': %w", mount, retired, err)
+	}
+	if err := c.vault.Sys().RemountWithContext(ctx, staging, mount); err != nil {
+		if restoreErr := c.vault.Sys().RemountWithContext(ctx, retired, mount); restoreErr != nil {
+			c.logf(LogError, "Не удалось вернуть engine '%s' на место: %v, копия находится в '%s'", mount, restoreErr, retired)
+		}
+		return fmt.Errorf("не удалось переименовать staging engine '%s' в '%s': %w", staging, mount, err)
+	}
+	if err := c.vault.Sys().UnmountWithContext(ctx, retired+"/"); err != nil {
+		c.logf(LogError, "Не удалось удалить старую копию '%s': %v", retired, err)
+	}
+	c.logf(LogInfo, "Engine '%s' заменен новой копией из staging", mount)
+	return nil
+}
+
+// clusterID запрашивает cluster_id экземпляра Vault. sys/health доступен только в root namespace
+func (c *Client) clusterID(ctx context.Context) (string, error) {
+	resp, err := c.vault.WithNamespace("").Logical().ReadRawWithContext(ctx, "/sys/health")
+	if err != nil {
+		return "", fmt.Errorf("ошибка при запросе /sys/health: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("ошибка декодирования ответа: %w", err)
+	}
+	if clusterID, ok := result["cluster_id"].(string); ok {
+		return clusterID, nil
+	}
+	return "", fmt.Errorf("cluster_id не найден в ответе")
+}
+
+// checkDistinct проверяет, что источник и копия - разные Vault. Один и тот же кластер допустим,
+// если копирование идет между разными namespace. Иначе возвращается ErrSameVault
+func checkDistinct(ctx context.Context, src, dst *Client) error {
+	if src.vault.Address() == dst.vault.Address() && src.vault.Namespace() == dst.vault.Namespace() {
+		return ErrSameVault
+	}
+	dstID, err := dst.clusterID(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке ClusterID: %w", err)
+	}
+	srcID, err := src.clusterID(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при проверке ClusterID: %w", err)
+	}
+	if srcID != dstID {
+		return nil
+	}
+	if src.vault.Namespace() != dst.vault.Namespace() {
+		src.logf(LogInfo, "Копирование внутри одного кластера между namespace '%s' и '%s'", src.vault.Namespace(), dst.vault.Namespace())
+		return nil
+	}
+	return ErrSameVault
+}
@@ -0,0 +1,71 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// RunParallel выполняет fn для каждого элемента items не более чем в workers горутинах.
+// Результаты возвращаются в порядке items, поэтому итоги и отчеты не зависят от того,
+// в каком порядке завершились запросы. После первой ошибки или отмены ctx новые элементы
+// не запускаются, возвращается ошибка элемента с наименьшим номером. Логи внутри fn идут
+// в порядке завершения, поэтому сообщения по элементам выводятся по результатам или в Debug
+func RunParallel[T, R any](ctx context.Context, workers int, items []T, fn func(context.Context, T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	var failed atomic.Bool
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range next {
+				if failed.Load() {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					errs[index] = err
+					failed.Store(true)
+					continue
+				}
+				results[index], errs[index] = fn(ctx, items[index])
+				if errs[index] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	for index := range items {
+		next <- index
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// parallel выполняет fn для items с числом горутин из Options.Concurrency
+func parallel[T, R any](ctx context.Context, c *Client, items []T, fn func(context.Context, T) (R, error)) ([]R, error) {
+	return RunParallel(ctx, c.opts.Concurrency, items, fn)
+}
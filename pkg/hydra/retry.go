@@ -0,0 +1,122 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-retryablehttp"
+	vault "github.com/hashicorp/vault/api"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy - политика повторов запросов к Vault и другим HTTP API
+type RetryPolicy struct {
+	Attempts int           // Всего попыток, включая первую
+	WaitMin  time.Duration // Пауза перед первым повтором, дальше удваивается
+	WaitMax  time.Duration // Наибольшая пауза между попытками
+	Timeout  time.Duration // Таймаут одной попытки
+}
+
+// DefaultRetryPolicy - политика повторов по умолчанию
+var DefaultRetryPolicy = RetryPolicy{Attempts: 5, WaitMin: 500 * time.Millisecond, WaitMax: 30 * time.Second, Timeout: 60 * time.Second}
+
+// checkRetry возвращает функцию, которая решает, повторять ли запрос. Повторяются только временные
+// ошибки сети, 429 и 5xx. Ошибки сертификата, схемы URL и редиректов retryablehttp.DefaultRetryPolicy
+// считает постоянными, 501 Not Implemented от повтора не исправится
+func (p RetryPolicy) checkRetry(log LogFunc) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if err != nil {
+			retry, _ := retryablehttp.DefaultRetryPolicy(ctx, nil, err)
+			log.logf(LogDebug, "Запрос не выполнен: %v", err)
+			return retry, nil
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented) {
+			log.logf(LogDebug, "%s %s: ответ %d", resp.Request.Method, resp.Request.URL.Redacted(), resp.StatusCode)
+			return true, nil
+		}
+		return false, nil
+	}
+}
+
+// backoff возвращает функцию паузы перед повтором attempt (с 0): WaitMin, удвоенная на каждом повторе,
+// не больше WaitMax, со случайным разбросом до половины паузы. На 429 и 503 сервер может
+// сообщить в Retry-After, когда повторить - тогда пауза берется из заголовка
+func (p RetryPolicy) backoff(log LogFunc) retryablehttp.Backoff {
+	return func(_, _ time.Duration, attempt int, resp *http.Response) time.Duration {
+		wait := p.WaitMax
+		if attempt < 32 && p.WaitMin<<attempt < p.WaitMax {
+			wait = p.WaitMin << attempt
+		}
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+		}
+		log.logf(LogDebug, "Попытка %d из %d не удалась, повтор через %s", attempt+1, p.Attempts, wait.Round(time.Millisecond))
+		return wait
+	}
+}
+
+// VaultConfig возвращает настройки клиента Vault с политикой повторов, таймаутом попытки и TLS.
+// tlsConfig может быть nil
+func (p RetryPolicy) VaultConfig(address string, tlsConfig *vault.TLSConfig, log LogFunc) (*vault.Config, error) {
+	config := &vault.Config{
+		Address:      address,
+		MaxRetries:   p.Attempts - 1,
+		MinRetryWait: p.WaitMin,
+		MaxRetryWait: p.WaitMax,
+		CheckRetry:   p.checkRetry(log),
+		Backoff:      p.backoff(log),
+	}
+	if tlsConfig == nil {
+		tlsConfig = &vault.TLSConfig{}
+	}
+	if err := config.ConfigureTLS(tlsConfig); err != nil {
+		return nil, err
+	}
+	// Таймаут задается на каждую попытку, а не на запрос вместе с повторами
+	config.HttpClient.Timeout = p.Timeout
+	return config, nil
+}
+
+// HTTPClient возвращает http.Client с политикой повторов и таймаутом попытки.
+// transport может быть nil - тогда используется http.DefaultTransport
+func (p RetryPolicy) HTTPClient(transport http.RoundTripper, log LogFunc) *http.Client {
+	client := retryablehttp.NewClient()
+	client.HTTPClient = &http.Client{Transport: transport, Timeout: p.Timeout}
+	client.RetryMax = p.Attempts - 1
+	client.RetryWaitMin = p.WaitMin
+	client.RetryWaitMax = p.WaitMax
+	client.CheckRetry = p.checkRetry(log)
+	client.Backoff = p.backoff(log)
+	// Ответ последней попытки возвращается как есть, код ответа проверяет вызывающий
+	client.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	client.Logger = nil
+	return client.StandardClient()
+}
+
+// Validate проверяет параметры политики
+func (p RetryPolicy) Validate() error {
+	switch {
+	case p.Attempts < 1:
+		return fmt.Errorf("число попыток должно быть не меньше 1: %d", p.Attempts)
+	case p.WaitMin <= 0 || p.Timeout <= 0:
+		return fmt.Errorf("пауза перед повтором и таймаут попытки должны быть больше нуля")
+	case p.WaitMax < p.WaitMin:
+		return fmt.Errorf("наибольшая пауза %s меньше паузы перед первым повтором %s", p.WaitMax, p.WaitMin)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretStore - хранилище секретов KV. Его реализуют Client (Vault KV v1 и v2), MemoryStore и FileStore,
+// поэтому чтение секретов, резервное копирование и сверку можно выполнять без Vault
+type SecretStore interface {
+	// Read читает секрет, для отсутствующего секрета возвращает ErrNotFound
+	Read(ctx context.Context, path string) (map[string]interface{}, error)
+	// Write записывает секрет, для хранилища с версиями - как новую версию
+	Write(ctx context.Context, path string, data map[string]interface{}) error
+	// Walk рекурсивно возвращает пути всех секретов внутри root, для отсутствующего пути - пустой список
+	Walk(ctx context.Context, root string) ([]string, error)
+	// Delete удаляет секрет вместе со всеми версиями
+	Delete(ctx context.Context, path string) error
+	// Metadata возвращает метаданные секрета со списком версий, для секрета без версий - ErrNotKVv2
+	Metadata(ctx context.Context, path string) (*Metadata, error)
+	// ReadVersion читает указанную версию секрета
+	ReadVersion(ctx context.Context, path string, version int) (map[string]interface{}, error)
+}
+
+var (
+	_ SecretStore = (*Client)(nil)
+	_ SecretStore = (*MemoryStore)(nil)
+	_ SecretStore = (*FileStore)(nil)
+)
+
+// MemoryStore - хранилище секретов в памяти с историей версий, как у KV v2. Пути можно указывать
+// как с data после точки монтирования, так и без: myns/data/app/db и myns/app/db - один секрет.
+// Безопасно для использования из нескольких горутин
+type MemoryStore struct {
+	mu      sync.RWMutex
+	secrets map[string]*memorySecret
+}
+
+// memorySecret - секрет MemoryStore со всеми версиями
+type memorySecret struct {
+	metadata Metadata
+	versions map[int]map[string]interface{}
+}
+
+// NewMemoryStore создает хранилище в памяти с секретами secrets (путь -> данные)
+func NewMemoryStore(secrets map[string]map[string]interface{}) *MemoryStore {
+	store := &MemoryStore{secrets: map[string]*memorySecret{}}
+	for path, data := range secrets {
+		store.put(path, data)
+	}
+	return store
+}
+
+// storeKey приводит путь к виду без data/metadata после первого сегмента и крайних /
+func storeKey(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 1 && (parts[1] == "data" || parts[1] == "metadata") {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// key возвращает ключ секрета для пути. Engine в хранилище нет, поэтому data или metadata убирается
+// там, где без него путь указывает на секрет или папку хранилища: team/kv/data/app/db находит секрет
+// team/kv/app/db вложенного engine. Для новых путей точкой монтирования считается первый сегмент, как в storeKey.
+// Вызывается под s.mu
+func (s *MemoryStore) key(path string) string {
+	key := strings.Trim(path, "/")
+	if s.known(key) {
+		return key
+	}
+	parts := strings.Split(key, "/")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "data" || parts[i] == "metadata" {
+			if candidate := strings.Join(append(parts[:i:i], parts[i+1:]...), "/"); s.known(candidate) {
+				return candidate
+			}
+		}
+	}
+	return storeKey(path)
+}
+
+// known сообщает, что в хранилище есть секрет key или секреты внутри него
+func (s *MemoryStore) known(key string) bool {
+	if _, ok := s.secrets[key]; ok {
+		return true
+	}
+	for other := range s.secrets {
+		if underRoot(other, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyData возвращает копию данных секрета, чтобы изменения у вызывающего не попадали в хранилище
+func copyData(data map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		result[key] = value
+	}
+	return result
+}
+
+// put записывает новую версию секрета
+func (s *MemoryStore) put(path string, data map[string]interface{}) {
+	key := s.key(path)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	secret, ok := s.secrets[key]
+	if !ok {
+		secret = &memorySecret{metadata: Metadata{CreatedTime: now}, versions: map[int]map[string]interface{}{}}
+		s.secrets[key] = secret
+	}
+	version := secret.metadata.CurrentVersion + 1
+	secret.versions[version] = copyData(data)
+	secret.metadata.CurrentVersion = version
+	secret.metadata.UpdatedTime = now
+	secret.metadata.Versions = append(secret.metadata.Versions, Version{Version: version, CreatedTime: now})
+}
+
+// Read читает последнюю версию секрета
+func (s *MemoryStore) Read(_ context.Context, path string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[s.key(path)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	return copyData(secret.versions[secret.metadata.CurrentVersion]), nil
+}
+
+// Write записывает секрет как новую версию
+func (s *MemoryStore) Write(_ context.Context, path string, data map[string]interface{}) error {
+	if storeKey(path) == "" {
+		return fmt.Errorf("не задан путь секрета")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(path, data)
+	return nil
+}
+
+// Walk возвращает отсортированные пути секретов внутри root, пустой root - все секреты
+func (s *MemoryStore) Walk(_ context.Context, root string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	root = s.key(root)
+	var paths []string
+	for key := range s.secrets {
+		if root == "" || underRoot(key, root) {
+			paths = append(paths, key)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Delete удаляет секрет со всеми версиями, отсутствующий секрет не считается ошибкой
+func (s *MemoryStore) Delete(_ context.Context, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, s.key(path))
+	return nil
+}
+
+// Metadata возвращает метаданные секрета со списком версий
+func (s *MemoryStore) Metadata(_ context.Context, path string) (*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[s.key(path)]
+	if !ok {
+		return nil, fmt.Errorf("метаданные %s не найдены: %w", path, ErrNotKVv2)
+	}
+	metadata := secret.metadata
+	metadata.Versions = append([]Version(nil), secret.metadata.Versions...)
+	return &metadata, nil
+}
+
+// ReadVersion читает указанную версию секрета
+func (s *MemoryStore) ReadVersion(_ context.Context, path string, version int) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[s.key(path)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	data, ok := secret.versions[version]
+	if !ok {
+		return nil, fmt.Errorf("версия %d секрета %s не найдена: %w", version, path, ErrNotFound)
+	}
+	return copyData(data), nil
+}
+
+// snapshot возвращает последние версии всех секретов
+func (s *MemoryStore) snapshot() map[string]map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make(map[string]map[string]interface{}, len(s.secrets))
+	for key, secret := range s.secrets {
+		result[key] = copyData(secret.versions[secret.metadata.CurrentVersion])
+	}
+	return result
+}
+
+// String возвращает описание хранилища для логов и отчетов
+func (s *MemoryStore) String() string {
+	return "memory"
+}
+
+// describeStore возвращает описание хранилища для логов и отчетов
+func describeStore(store SecretStore) string {
+	if stringer, ok := store.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", store)
+}
+
+// storeLog возвращает журнал первого клиента Vault из списка, другие хранилища сообщений не выводят
+func storeLog(stores ...SecretStore) LogFunc {
+	for _, store := range stores {
+		if client, ok := store.(*Client); ok {
+			return client.opts.Log
+		}
+	}
+	return nil
+}
+
+// storeConcurrency возвращает число параллельных запросов к хранилищу
+func storeConcurrency(store SecretStore) int {
+	if client, ok := store.(*Client); ok {
+		return client.opts.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// readData читает данные секрета, для отсутствующего секрета возвращает nil без ошибки
+func readData(ctx context.Context, store SecretStore, path string) (map[string]interface{}, error) {
+	data, err := store.Read(ctx, path)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return data, err
+}
@@ -0,0 +1,513 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package vaulttest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// mount - engine Vault. Секреты хранятся только у engine KV
+type mount struct {
+	Type        string
+	Version     string // Версия KV: 1 или 2
+	Description string
+
+	// Настройки KV v2 из <mount>/config
+	maxVersions        int
+	casRequired        bool
+	deleteVersionAfter string
+
+	kv1 map[string]map[string]interface{}
+	kv2 map[string]*secret
+}
+
+// secret - секрет KV v2 со всеми версиями. Версии, удаленные по max_versions, хранятся как nil
+type secret struct {
+	versions           []*version
+	customMetadata     map[string]interface{}
+	maxVersions        int
+	casRequired        bool
+	deleteVersionAfter string
+	created            string
+	updated            string
+}
+
+// version - версия секрета KV v2
+type version struct {
+	data      map[string]interface{}
+	created   string
+	deleted   string
+	destroyed bool
+}
+
+// newMount создает engine, для KV - с пустым хранилищем секретов
+func newMount(mountType, kvVersion, description string) *mount {
+	return &mount{
+		Type:        mountType,
+		Version:     kvVersion,
+		Description: description,
+		kv1:         map[string]map[string]interface{}{},
+		kv2:         map[string]*secret{},
+	}
+}
+
+// options возвращает options engine, для KV - с версией
+func (m *mount) options() map[string]interface{} {
+	if m.Type != "kv" {
+		return nil
+	}
+	return map[string]interface{}{"version": m.Version}
+}
+
+// info возвращает описание engine в формате sys/mounts
+func (m *mount) info() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        m.Type,
+		"description": m.Description,
+		"options":     m.options(),
+		"config":      map[string]interface{}{"default_lease_ttl": 0, "max_lease_ttl": 0},
+	}
+}
+
+// defaultMaxVersions - число хранимых версий, если max_versions не задан ни у секрета, ни у engine
+const defaultMaxVersions = 10
+
+// version возвращает версию по номеру или nil, если ее нет или она удалена по max_versions
+func (sec *secret) version(number int) *version {
+	if number < 1 || number > len(sec.versions) {
+		return nil
+	}
+	return sec.versions[number-1]
+}
+
+// oldestVersion возвращает номер самой старой хранимой версии
+func (sec *secret) oldestVersion() int {
+	for i, v := range sec.versions {
+		if v != nil {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// metadata возвращает метаданные секрета KV v2 в формате <mount>/metadata/<path>
+func (sec *secret) metadata() map[string]interface{} {
+	versions := map[string]interface{}{}
+	for i, v := range sec.versions {
+		if v != nil {
+			versions[fmt.Sprint(i+1)] = map[string]interface{}{"created_time": v.created, "deletion_time": v.deleted, "destroyed": v.destroyed}
+		}
+	}
+	return map[string]interface{}{
+		"current_version":      len(sec.versions),
+		"oldest_version":       sec.oldestVersion(),
+		"max_versions":         sec.maxVersions,
+		"cas_required":         sec.casRequired,
+		"delete_version_after": sec.deleteVersionAfter,
+		"created_time":         sec.created,
+		"updated_time":         sec.updated,
+		"custom_metadata":      sec.customMetadata,
+		"versions":             versions,
+	}
+}
+
+// updateMetadata меняет настройки секрета из тела запроса к <mount>/metadata/<path>
+func (sec *secret) updateMetadata(body map[string]interface{}) {
+	if value, ok := body["custom_metadata"].(map[string]interface{}); ok {
+		sec.customMetadata = value
+	}
+	switch value := body["max_versions"].(type) {
+	case float64:
+		sec.maxVersions = int(value)
+	case int:
+		sec.maxVersions = value
+	}
+	if value, ok := body["cas_required"].(bool); ok {
+		sec.casRequired = value
+	}
+	if value, ok := body["delete_version_after"].(string); ok {
+		sec.deleteVersionAfter = value
+	}
+}
+
+// Mount подключает engine KV версии kvVersion (1 или 2) по пути path
+func (s *Server) Mount(path string, kvVersion int) {
+	s.t.Helper()
+	if kvVersion != 1 && kvVersion != 2 {
+		s.t.Fatalf("vaulttest: неизвестная версия KV %d", kvVersion)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mounts[strings.Trim(path, "/")+"/"] = newMount("kv", fmt.Sprint(kvVersion), "")
+}
+
+// Put записывает секрет по пути без data: secret/app/db. В KV v2 запись добавляет новую версию
+func (s *Server) Put(path string, data map[string]interface{}) {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, key := s.findMount(path)
+	if m == nil || m.Type != "kv" || key == "" {
+		s.t.Fatalf("vaulttest: нет engine KV для пути %s", path)
+	}
+	if m.Version == "1" {
+		m.kv1[key] = copyData(data)
+		return
+	}
+	s.putVersion(m, key, data)
+}
+
+// Get возвращает последнюю версию секрета по пути без data или nil, если секрета нет или версия удалена
+func (s *Server) Get(path string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, key := s.findMount(path)
+	switch {
+	case m == nil:
+		return nil
+	case m.Version == "1":
+		return copyData(m.kv1[key])
+	}
+	sec := m.kv2[key]
+	if sec == nil || len(sec.versions) == 0 {
+		return nil
+	}
+	latest := sec.versions[len(sec.versions)-1]
+	if latest.deleted != "" || latest.destroyed {
+		return nil
+	}
+	return copyData(latest.data)
+}
+
+// Versions возвращает номер последней версии секрета KV v2 (current_version), для KV v1 и отсутствующего секрета - 0
+func (s *Server) Versions(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, key := s.findMount(path)
+	if m == nil || m.kv2[key] == nil {
+		return 0
+	}
+	return len(m.kv2[key].versions)
+}
+
+// Metadata возвращает метаданные секрета KV v2 по пути без data в формате <mount>/metadata/<path>,
+// для KV v1 и отсутствующего секрета - nil
+func (s *Server) Metadata(path string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, key := s.findMount(path)
+	if m == nil || m.kv2[key] == nil {
+		return nil
+	}
+	return m.kv2[key].metadata()
+}
+
+// SetMetadata меняет настройки секрета KV v2 так же, как запись в <mount>/metadata/<path>:
+// custom_metadata, max_versions, cas_required и delete_version_after
+func (s *Server) SetMetadata(path string, settings map[string]interface{}) {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, key := s.findMount(path)
+	if m == nil || m.kv2[key] == nil {
+		s.t.Fatalf("vaulttest: нет секрета KV v2 %s", path)
+	}
+	m.kv2[key].updateMetadata(settings)
+}
+
+// Paths возвращает отсортированные пути секретов engine mount без data: secret/app/db
+func (s *Server) Paths(mountPath string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := strings.Trim(mountPath, "/") + "/"
+	m := s.mounts[name]
+	if m == nil {
+		return nil
+	}
+	var paths []string
+	for key := range m.kv1 {
+		paths = append(paths, name+key)
+	}
+	for key, sec := range m.kv2 {
+		if len(sec.versions) > 0 {
+			paths = append(paths, name+key)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// findMount возвращает engine с самым длинным совпадающим путем и путь внутри него
+func (s *Server) findMount(path string) (*mount, string) {
+	path = strings.Trim(path, "/") + "/"
+	best := ""
+	for name := range s.mounts {
+		if strings.HasPrefix(path, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return nil, ""
+	}
+	return s.mounts[best], strings.Trim(strings.TrimPrefix(path, best), "/")
+}
+
+// putVersion добавляет версию секрета KV v2 и возвращает ее номер. Как в Vault, версии сверх
+// max_versions секрета (или engine, или 10 по умолчанию) удаляются, начиная с самой старой
+func (s *Server) putVersion(m *mount, key string, data map[string]interface{}) int {
+	sec := m.kv2[key]
+	if sec == nil {
+		sec = &secret{customMetadata: map[string]interface{}{}, created: s.now()}
+		m.kv2[key] = sec
+	}
+	now := s.now()
+	sec.versions = append(sec.versions, &version{data: copyData(data), created: now})
+	sec.updated = now
+	limit := sec.maxVersions
+	if limit == 0 {
+		limit = m.maxVersions
+	}
+	if limit == 0 {
+		limit = defaultMaxVersions
+	}
+	for oldest := sec.oldestVersion(); len(sec.versions)-oldest+1 > limit; oldest++ {
+		sec.versions[oldest-1] = nil
+	}
+	return len(sec.versions)
+}
+
+// copyData возвращает копию данных секрета
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		result[key] = value
+	}
+	return result
+}
+
+// kv отвечает на запросы к engine KV
+func (s *Server) kv(w http.ResponseWriter, req request) {
+	m, rest := s.findMount(req.path)
+	if m == nil || m.Type != "kv" {
+		replyError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	if m.Version == "1" {
+		s.kv1(w, req, m, rest)
+		return
+	}
+	s.kv2(w, req, m, rest)
+}
+
+// kv1 отвечает на запросы к KV v1: путь секрета идет сразу после точки монтирования
+func (s *Server) kv1(w http.ResponseWriter, req request, m *mount, key string) {
+	switch req.method {
+	case "LIST":
+		paths := make([]string, 0, len(m.kv1))
+		for path := range m.kv1 {
+			paths = append(paths, path)
+		}
+		replyKeys(w, listKeys(paths, folder(key)))
+	case http.MethodGet:
+		data, ok := m.kv1[key]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"data": data})
+	case http.MethodDelete:
+		delete(m.kv1, key)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		if key == "" {
+			replyError(w, http.StatusMethodNotAllowed, "unsupported operation")
+			return
+		}
+		m.kv1[key] = copyData(req.body)
+		reply(w, http.StatusNoContent, nil)
+	}
+}
+
+// kv2 отвечает на запросы к KV v2: после точки монтирования идет data, metadata, config,
+// delete, destroy или undelete. Для других путей Vault возвращает 404 с предупреждением invalidV2Path
+func (s *Server) kv2(w http.ResponseWriter, req request, m *mount, rest string) {
+	if rest == "config" {
+		s.kv2Config(w, req, m)
+		return
+	}
+	operation, key, _ := strings.Cut(rest, "/")
+	switch operation {
+	case "data":
+		s.kv2Data(w, req, m, key)
+	case "metadata":
+		s.kv2Metadata(w, req, m, key)
+	case "delete", "destroy", "undelete":
+		s.kv2Versions(w, req, m, operation, key)
+	default:
+		reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}, "warnings": []string{invalidV2Path}})
+	}
+}
+
+// kv2Config отвечает на <mount>/config
+func (s *Server) kv2Config(w http.ResponseWriter, req request, m *mount) {
+	if req.method == http.MethodGet {
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"max_versions": m.maxVersions, "cas_required": m.casRequired, "delete_version_after": m.deleteVersionAfter,
+		}})
+		return
+	}
+	if value, ok := req.body["max_versions"].(float64); ok {
+		m.maxVersions = int(value)
+	}
+	if value, ok := req.body["cas_required"].(bool); ok {
+		m.casRequired = value
+	}
+	if value, ok := req.body["delete_version_after"].(string); ok {
+		m.deleteVersionAfter = value
+	}
+	reply(w, http.StatusNoContent, nil)
+}
+
+// kv2Data отвечает на <mount>/data/<path>: чтение версии, запись новой версии с check-and-set и удаление последней версии
+func (s *Server) kv2Data(w http.ResponseWriter, req request, m *mount, key string) {
+	sec := m.kv2[key]
+	switch req.method {
+	case http.MethodGet:
+		if sec == nil || len(sec.versions) == 0 {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		number := len(sec.versions)
+		if values := req.query["version"]; len(values) > 0 && values[0] != "0" {
+			fmt.Sscan(values[0], &number)
+		}
+		v := sec.version(number)
+		if v == nil {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		if v.deleted != "" || v.destroyed {
+			reply(w, http.StatusNotFound, map[string]interface{}{"data": map[string]interface{}{
+				"data":     nil,
+				"metadata": map[string]interface{}{"version": number, "created_time": v.created, "deletion_time": v.deleted, "destroyed": v.destroyed},
+			}})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"data":     v.data,
+			"metadata": map[string]interface{}{"version": number, "created_time": v.created, "deletion_time": "", "destroyed": false, "custom_metadata": sec.customMetadata},
+		}})
+	case http.MethodDelete:
+		if sec != nil && len(sec.versions) > 0 {
+			sec.versions[len(sec.versions)-1].deleted = s.now()
+		}
+		reply(w, http.StatusNoContent, nil)
+	case "LIST":
+		reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}, "warnings": []string{invalidV2Path}})
+	default:
+		data, ok := req.body["data"].(map[string]interface{})
+		if !ok {
+			replyError(w, http.StatusBadRequest, "no data provided")
+			return
+		}
+		current := 0
+		if sec != nil {
+			current = len(sec.versions)
+		}
+		options, _ := req.body["options"].(map[string]interface{})
+		cas, hasCAS := options["cas"].(float64)
+		casRequired := m.casRequired || (sec != nil && sec.casRequired)
+		if (hasCAS && int(cas) != current) || (casRequired && !hasCAS) {
+			replyError(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+			return
+		}
+		number := s.putVersion(m, key, data)
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"version": number, "created_time": m.kv2[key].updated, "deletion_time": "", "destroyed": false,
+		}})
+	}
+}
+
+// kv2Metadata отвечает на <mount>/metadata/<path>: список секретов, метаданные, их изменение и удаление секрета
+func (s *Server) kv2Metadata(w http.ResponseWriter, req request, m *mount, key string) {
+	sec := m.kv2[key]
+	switch req.method {
+	case "LIST":
+		paths := make([]string, 0, len(m.kv2))
+		for path := range m.kv2 {
+			paths = append(paths, path)
+		}
+		replyKeys(w, listKeys(paths, folder(key)))
+	case http.MethodGet:
+		if sec == nil {
+			reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"data": sec.metadata()})
+	case http.MethodDelete:
+		delete(m.kv2, key)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		if sec == nil {
+			sec = &secret{customMetadata: map[string]interface{}{}, created: s.now()}
+			m.kv2[key] = sec
+		}
+		sec.updateMetadata(req.body)
+		reply(w, http.StatusNoContent, nil)
+	}
+}
+
+// kv2Versions отвечает на <mount>/delete, destroy и undelete для списка версий из тела запроса
+func (s *Server) kv2Versions(w http.ResponseWriter, req request, m *mount, operation, key string) {
+	sec := m.kv2[key]
+	if sec == nil {
+		reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	numbers, _ := req.body["versions"].([]interface{})
+	for _, value := range numbers {
+		number, _ := value.(float64)
+		v := sec.version(int(number))
+		if v == nil {
+			continue
+		}
+		switch operation {
+		case "delete":
+			v.deleted = s.now()
+		case "destroy":
+			v.destroyed = true
+			v.data = nil
+		case "undelete":
+			v.deleted = ""
+		}
+	}
+	reply(w, http.StatusNoContent, nil)
+}
+
+// folder возвращает префикс папки для списка: пустой путь - корень engine
+func folder(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+// replyKeys отвечает списком ключей, пустой список - 404, как в Vault
+func replyKeys(w http.ResponseWriter, keys []string) {
+	if len(keys) == 0 {
+		reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+	reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+}
@@ -0,0 +1,514 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+// Package vaulttest - поддельный Vault для тестов поверх httptest.Server. Эмулирует engine KV v1 и v2
+// (в том числе предупреждение о неверном пути версионного engine, по которому hydra подбирает путь с data),
+// sys/init, sys/unseal, sys/seal-status, sys/health, sys/mounts и авторизацию по JWT и Kubernetes.
+//
+// Состояние хранится в памяти сервера, его можно заполнить и проверить методами Server. Запросы
+// без действующего токена отклоняются с 403, запросы к неинициализированному или запечатанному Vault - с 503
+package vaulttest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// invalidV2Path - предупреждение Vault при обращении к KV v2 по пути без data или metadata
+const invalidV2Path = "Invalid path for a versioned K/V secrets engine. See the API docs for the appropriate API endpoints to use. If using the Vault CLI, use 'vault kv get' for this operation."
+
+// Server - поддельный Vault. Безопасен для использования из нескольких горутин
+type Server struct {
+	URL string // Адрес сервера для VAULT_ADDR
+
+	t           testing.TB
+	server      *httptest.Server
+	mu          sync.Mutex
+	initialized bool
+	sealed      bool
+	threshold   int
+	keys        [][]byte
+	provided    map[string]bool
+	rootToken   string
+	tokens      map[string]bool
+	logins      map[string]string // "kubernetes/app" -> JWT, с которым роль app пускают по auth/kubernetes/login
+	mounts      map[string]*mount
+	clock       time.Time
+	issued      int
+	requests    []string
+}
+
+// NewServer запускает инициализированный и разблокированный Vault с KV v2 по пути secret и корневым
+// токеном RootToken(). Ключи разблокировки (5 ключей, порог 3) возвращает UnsealKeys.
+// Сервер останавливается в конце теста
+func NewServer(t testing.TB) *Server {
+	s := NewUninitializedServer(t)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.init(5, 3); err != nil {
+		t.Fatal(err)
+	}
+	s.sealed = false
+	s.mounts["secret/"] = newMount("kv", "2", "key/value secret storage")
+	return s
+}
+
+// NewUninitializedServer запускает Vault, который еще не инициализирован, как после первого запуска
+func NewUninitializedServer(t testing.TB) *Server {
+	s := &Server{
+		t:        t,
+		sealed:   true,
+		provided: map[string]bool{},
+		tokens:   map[string]bool{},
+		logins:   map[string]string{},
+		mounts: map[string]*mount{
+			"cubbyhole/": {Type: "cubbyhole", Description: "per-token private secret storage"},
+			"identity/":  {Type: "identity", Description: "identity store"},
+			"sys/":       {Type: "system", Description: "system endpoints used for control, policy and debugging"},
+		},
+		clock: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// RootToken возвращает корневой токен, пустой до инициализации
+func (s *Server) RootToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rootToken
+}
+
+// UnsealKeys возвращает ключи разблокировки в base64, как keys_base64 ответа sys/init
+func (s *Server) UnsealKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, len(s.keys))
+	for i, key := range s.keys {
+		keys[i] = base64.StdEncoding.EncodeToString(key)
+	}
+	return keys
+}
+
+// Initialized сообщает, инициализирован ли Vault
+func (s *Server) Initialized() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.initialized
+}
+
+// Sealed сообщает, запечатан ли Vault
+func (s *Server) Sealed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sealed
+}
+
+// Seal запечатывает Vault, после этого его нужно разблокировать ключами UnsealKeys
+func (s *Server) Seal() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sealed = true
+	s.provided = map[string]bool{}
+}
+
+// AddLogin разрешает авторизацию по JWT: запрос auth/<path>/login с ролью role и токеном jwt
+// получает новый токен. path - путь метода авторизации, например kubernetes или jwt
+func (s *Server) AddLogin(path, role, jwt string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logins[strings.Trim(path, "/")+"/"+role] = jwt
+}
+
+// Requests возвращает запросы к серверу в порядке поступления: метод (LIST для списков) и путь без /v1/
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ResetRequests очищает список запросов
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// now возвращает время для метаданных. Часы сервера идут на секунду за вызов, чтобы время версий не совпадало
+func (s *Server) now() string {
+	s.clock = s.clock.Add(time.Second)
+	return s.clock.Format(time.RFC3339)
+}
+
+// init инициализирует Vault: создает ключи разблокировки и корневой токен. Vault остается запечатанным
+func (s *Server) init(shares, threshold int) (map[string]interface{}, error) {
+	switch {
+	case s.initialized:
+		return nil, fmt.Errorf("Vault is already initialized")
+	case shares < 1 || threshold < 1 || threshold > shares:
+		return nil, fmt.Errorf("invalid seal configuration: threshold must be between 1 and %d", shares)
+	case shares > 1 && threshold == 1:
+		return nil, fmt.Errorf("invalid seal configuration: threshold must be greater than one for multiple shares")
+	}
+	s.keys = make([][]byte, shares)
+	keysHex := make([]string, shares)
+	keysB64 := make([]string, shares)
+	for i := range s.keys {
+		s.keys[i] = randomBytes(32)
+		keysHex[i] = hex.EncodeToString(s.keys[i])
+		keysB64[i] = base64.StdEncoding.EncodeToString(s.keys[i])
+	}
+	s.threshold = threshold
+	s.rootToken = "hvs." + hex.EncodeToString(randomBytes(12))
+	s.tokens[s.rootToken] = true
+	s.initialized = true
+	return map[string]interface{}{"keys": keysHex, "keys_base64": keysB64, "root_token": s.rootToken}, nil
+}
+
+// randomBytes возвращает n случайных байт
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// reply отправляет ответ в JSON, body nil - ответ без тела
+func reply(w http.ResponseWriter, code int, body interface{}) {
+	if body == nil {
+		w.WriteHeader(code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// replyError отправляет ошибку в формате Vault
+func replyError(w http.ResponseWriter, code int, message string) {
+	reply(w, code, map[string]interface{}{"errors": []string{message}})
+}
+
+// request - разобранный запрос к API
+type request struct {
+	method string // GET, PUT, DELETE или LIST
+	path   string // путь без /v1/ и крайних /
+	body   map[string]interface{}
+	query  map[string][]string
+	token  string
+}
+
+// serveHTTP разбирает запрос и передает его обработчику по пути
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req := request{
+		method: r.Method,
+		path:   strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"),
+		query:  r.URL.Query(),
+		token:  r.Header.Get("X-Vault-Token"),
+	}
+	if req.method == http.MethodPost {
+		req.method = http.MethodPut
+	}
+	if req.method == "LIST" || (req.method == http.MethodGet && r.URL.Query().Get("list") == "true") {
+		req.method = "LIST"
+	}
+	if content, _ := io.ReadAll(r.Body); len(content) > 0 {
+		if err := json.Unmarshal(content, &req.body); err != nil {
+			replyError(w, http.StatusBadRequest, "failed to parse JSON input: "+err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req.method+" "+req.path)
+
+	switch {
+	case req.path == "sys/health":
+		s.health(w, req)
+		return
+	case req.path == "sys/init":
+		s.handleInit(w, req)
+		return
+	case req.path == "sys/seal-status":
+		reply(w, http.StatusOK, s.sealStatus())
+		return
+	case req.path == "sys/unseal":
+		s.unseal(w, req)
+		return
+	case !s.initialized || s.sealed:
+		replyError(w, http.StatusServiceUnavailable, "Vault is sealed")
+		return
+	case strings.HasPrefix(req.path, "auth/") && strings.HasSuffix(req.path, "/login"):
+		s.login(w, req)
+		return
+	case !s.tokens[req.token]:
+		replyError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(req.path, "auth/token/"):
+		s.token(w, req)
+	case req.path == "sys/mounts" || strings.HasPrefix(req.path, "sys/mounts/"):
+		s.handleMounts(w, req)
+	case req.path == "sys/remount" || strings.HasPrefix(req.path, "sys/remount/"):
+		s.remount(w, req)
+	default:
+		s.kv(w, req)
+	}
+}
+
+// health отвечает как sys/health: коды ответа можно переопределить параметрами запроса, как в Vault
+func (s *Server) health(w http.ResponseWriter, req request) {
+	code := http.StatusOK
+	switch {
+	case !s.initialized:
+		code = queryCode(req, "uninitcode", http.StatusNotImplemented)
+	case s.sealed:
+		code = queryCode(req, "sealedcode", http.StatusServiceUnavailable)
+	}
+	reply(w, code, map[string]interface{}{
+		"initialized": s.initialized,
+		"sealed":      s.sealed,
+		"standby":     false,
+		"version":     "1.15.0",
+		"cluster_id":  "vaulttest-" + strings.TrimPrefix(s.URL, "http://"),
+	})
+}
+
+// queryCode возвращает код ответа из параметра запроса или значение по умолчанию
+func queryCode(req request, name string, fallback int) int {
+	var code int
+	if values := req.query[name]; len(values) > 0 {
+		if _, err := fmt.Sscan(values[0], &code); err == nil {
+			return code
+		}
+	}
+	return fallback
+}
+
+// handleInit отвечает на sys/init: GET - статус, PUT - инициализация
+func (s *Server) handleInit(w http.ResponseWriter, req request) {
+	if req.method == http.MethodGet {
+		reply(w, http.StatusOK, map[string]interface{}{"initialized": s.initialized})
+		return
+	}
+	shares, _ := req.body["secret_shares"].(float64)
+	threshold, _ := req.body["secret_threshold"].(float64)
+	resp, err := s.init(int(shares), int(threshold))
+	if err != nil {
+		replyError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reply(w, http.StatusOK, resp)
+}
+
+// sealStatus возвращает состояние печати в формате sys/seal-status
+func (s *Server) sealStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "shamir",
+		"initialized": s.initialized,
+		"sealed":      s.sealed,
+		"t":           s.threshold,
+		"n":           len(s.keys),
+		"progress":    len(s.provided),
+		"version":     "1.15.0",
+	}
+}
+
+// unseal принимает ключ разблокировки в hex или base64. Vault разблокируется, когда получено
+// threshold разных ключей; повторно переданный ключ не учитывается
+func (s *Server) unseal(w http.ResponseWriter, req request) {
+	if !s.initialized {
+		replyError(w, http.StatusBadRequest, "Vault is not initialized")
+		return
+	}
+	if reset, _ := req.body["reset"].(bool); reset {
+		s.provided = map[string]bool{}
+		reply(w, http.StatusOK, s.sealStatus())
+		return
+	}
+	if !s.sealed {
+		reply(w, http.StatusOK, s.sealStatus())
+		return
+	}
+	value, _ := req.body["key"].(string)
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil {
+		replyError(w, http.StatusBadRequest, "'key' must be a valid hex or base64 string")
+		return
+	}
+	index := -1
+	for i, known := range s.keys {
+		if string(known) == string(key) {
+			index = i
+		}
+	}
+	if index < 0 {
+		s.provided = map[string]bool{}
+		replyError(w, http.StatusBadRequest, "Error unsealing: invalid key")
+		return
+	}
+	s.provided[fmt.Sprint(index)] = true
+	if len(s.provided) >= s.threshold {
+		s.sealed = false
+		s.provided = map[string]bool{}
+	}
+	reply(w, http.StatusOK, s.sealStatus())
+}
+
+// login выдает токен по auth/<path>/login, если роль и JWT совпадают с заданными через AddLogin
+func (s *Server) login(w http.ResponseWriter, req request) {
+	path := strings.TrimSuffix(strings.TrimPrefix(req.path, "auth/"), "/login")
+	role, _ := req.body["role"].(string)
+	jwt, _ := req.body["jwt"].(string)
+	expected, ok := s.logins[path+"/"+role]
+	if !ok || jwt == "" || jwt != expected {
+		replyError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	s.issued++
+	token := fmt.Sprintf("hvs.login-%d", s.issued)
+	s.tokens[token] = true
+	reply(w, http.StatusOK, map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token":   token,
+			"accessor":       fmt.Sprintf("accessor-%d", s.issued),
+			"policies":       []string{"default"},
+			"lease_duration": 3600,
+			"renewable":      true,
+			"metadata":       map[string]string{"role": role},
+		},
+	})
+}
+
+// token отвечает на lookup-self, renew-self и revoke-self
+func (s *Server) token(w http.ResponseWriter, req request) {
+	switch strings.TrimPrefix(req.path, "auth/token/") {
+	case "lookup-self":
+		ttl := 3600
+		if req.token == s.rootToken {
+			ttl = 0
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"id": req.token, "ttl": ttl}})
+	case "renew-self":
+		reply(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{"client_token": req.token, "lease_duration": 3600, "renewable": true}})
+	case "revoke-self":
+		delete(s.tokens, req.token)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		replyError(w, http.StatusNotFound, "unsupported path")
+	}
+}
+
+// handleMounts отвечает на sys/mounts: список, подключение, отключение и настройки engine
+func (s *Server) handleMounts(w http.ResponseWriter, req request) {
+	if req.path == "sys/mounts" {
+		data := map[string]interface{}{}
+		for name, m := range s.mounts {
+			data[name] = m.info()
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"data": data})
+		return
+	}
+	name, tune := strings.CutSuffix(strings.TrimPrefix(req.path, "sys/mounts/"), "/tune")
+	name += "/"
+	m := s.mounts[name]
+	switch {
+	case tune && m == nil:
+		replyError(w, http.StatusBadRequest, "cannot fetch sysview for path "+name)
+	case tune && req.method == http.MethodGet:
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"description": m.Description, "options": m.options(), "default_lease_ttl": 2764800, "max_lease_ttl": 2764800,
+		}})
+	case tune:
+		if description, ok := req.body["description"].(string); ok {
+			m.Description = description
+		}
+		reply(w, http.StatusNoContent, nil)
+	case req.method == http.MethodDelete:
+		delete(s.mounts, name)
+		reply(w, http.StatusNoContent, nil)
+	case req.method == http.MethodGet && m != nil:
+		reply(w, http.StatusOK, map[string]interface{}{"data": m.info()})
+	case m != nil:
+		replyError(w, http.StatusBadRequest, "path is already in use at "+name)
+	default:
+		mountType, _ := req.body["type"].(string)
+		version := "1"
+		if options, ok := req.body["options"].(map[string]interface{}); ok && options["version"] != nil {
+			version = fmt.Sprint(options["version"])
+		}
+		if mountType == "kv-v2" {
+			mountType, version = "kv", "2"
+		}
+		description, _ := req.body["description"].(string)
+		s.mounts[name] = newMount(mountType, version, description)
+		reply(w, http.StatusNoContent, nil)
+	}
+}
+
+// remount переносит engine на другой путь. Перенос выполняется сразу, статус всегда success
+func (s *Server) remount(w http.ResponseWriter, req request) {
+	if strings.HasPrefix(req.path, "sys/remount/status/") {
+		reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"migration_id": strings.TrimPrefix(req.path, "sys/remount/status/"), "migration_info": map[string]interface{}{"status": "success"},
+		}})
+		return
+	}
+	from, _ := req.body["from"].(string)
+	to, _ := req.body["to"].(string)
+	from = strings.Trim(from, "/") + "/"
+	to = strings.Trim(to, "/") + "/"
+	if s.mounts[from] == nil || s.mounts[to] != nil {
+		replyError(w, http.StatusBadRequest, fmt.Sprintf("cannot remount %s to %s", from, to))
+		return
+	}
+	s.mounts[to] = s.mounts[from]
+	delete(s.mounts, from)
+	s.issued++
+	reply(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"migration_id": fmt.Sprintf("migration-%d", s.issued)}})
+}
+
+// listKeys возвращает ключи следующего уровня внутри prefix: секреты и папки с / на конце
+func listKeys(paths []string, prefix string) []string {
+	set := map[string]bool{}
+	for _, path := range paths {
+		rest, ok := strings.CutPrefix(path, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		set[rest] = true
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
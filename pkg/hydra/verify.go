@@ -0,0 +1,126 @@
+// Hydra Vault - Инструмент для упрощения интеграции с Hashicorp Vault на стадии CI/CD с поддержкой переменных окружения и аргументов для любых архитектур и операционных систем.
+// Автор: Kholodov Alexandr Sergeevich
+// maito: murcie1337@gmail.com
+// Copyright (c) 2025 Kholodov Alexandr Sergeevich
+//
+// Лицензия: MIT License
+
+package hydra
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// VerifyOptions - параметры сверки копии
+type VerifyOptions struct {
+	Paths   []string               // Сверяемые пути, * - все engine KV источника
+	Exclude func(path string) bool // Секреты, для которых возвращается true, не сверяются; nil - сверяются все
+}
+
+// VerifyReport - результат сверки секретов основного и вторичного Vault.
+// В отчет попадают только пути, значения секретов не выводятся
+type VerifyReport struct {
+	Created   string   `json:"created"`
+	Source    string   `json:"source"`
+	Target    string   `json:"target"`
+	Paths     []string `json:"paths"`
+	Checked   int      `json:"checked"`
+	Matched   int      `json:"matched"`
+	Missing   []string `json:"missing"`   // есть в основном Vault, нет во вторичном
+	Extra     []string `json:"extra"`     // есть только во вторичном Vault
+	Different []string `json:"different"` // данные отличаются
+	Drift     bool     `json:"drift"`
+}
+
+// Verify сравнивает секреты src и dst по хешу данных. Расхождения возвращаются в отчете, ошибка -
+// только если сверку не удалось выполнить
+func Verify(ctx context.Context, src, dst SecretStore, opts VerifyOptions) (*VerifyReport, error) {
+	roots, err := storeRoots(ctx, src, opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		Created:   time.Now().UTC().Format(time.RFC3339),
+		Source:    describeStore(src),
+		Target:    describeStore(dst),
+		Paths:     roots,
+		Missing:   []string{},
+		Extra:     []string{},
+		Different: []string{},
+	}
+	for _, root := range roots {
+		sourceHashes, err := secretHashes(ctx, src, root, opts.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		targetHashes, err := secretHashes(ctx, dst, root, opts.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		for path, hash := range sourceHashes {
+			report.Checked++
+			switch targetHash, ok := targetHashes[path]; {
+			case !ok:
+				report.Missing = append(report.Missing, path)
+			case targetHash != hash:
+				report.Different = append(report.Different, path)
+			default:
+				report.Matched++
+			}
+		}
+		for path := range targetHashes {
+			if _, ok := sourceHashes[path]; !ok {
+				report.Extra = append(report.Extra, path)
+			}
+		}
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Strings(report.Different)
+	report.Drift = len(report.Missing)+len(report.Extra)+len(report.Different) > 0
+	return report, nil
+}
+
+// secretHashes рекурсивно читает секреты по пути и возвращает sha256 данных каждого секрета
+func secretHashes(ctx context.Context, store SecretStore, root string, exclude func(string) bool) (map[string]string, error) {
+	paths, err := store.Walk(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	included := filterExcluded(storeLog(store), exclude, paths)
+	results, err := RunParallel(ctx, storeConcurrency(store), included, func(ctx context.Context, path string) (string, error) {
+		data, err := readData(ctx, store, path)
+		if err != nil {
+			return "", err
+		}
+		return secretHash(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(included))
+	for i, path := range included {
+		hashes[NormalizePath(path)] = results[i]
+	}
+	return hashes, nil
+}
+
+// secretHash возвращает sha256 данных секрета. json.Marshal сортирует ключи,
+// поэтому хеш не зависит от порядка ключей. Пустой и удаленный секрет равны
+func secretHash(data map[string]interface{}) (string, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(dataJSON)
+	return hex.EncodeToString(sum[:]), nil
+}